@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rafa-mori/goforge/config"
+	"github.com/spf13/cobra"
+)
+
+func ConfigCmd() *cobra.Command {
+	var configCmd = &cobra.Command{
+		Use: "config",
+		Annotations: GetDescriptions([]string{
+			"Manage the layered configuration.",
+			"Read and edit the configuration. Values are resolved from flags, environment variables, the user file, the project file and the manifest defaults, in that order.",
+		}, false),
+	}
+
+	configCmd.AddCommand(configGetCommand())
+	configCmd.AddCommand(configSetCommand())
+	configCmd.AddCommand(configListCommand())
+	configCmd.AddCommand(configUnsetCommand())
+	configCmd.AddCommand(configPathCommand())
+	configCmd.AddCommand(configValidateCommand())
+
+	return configCmd
+}
+
+func configGetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print the resolved value of a key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := config.Get()
+			if !c.IsSet(args[0]) {
+				return fmt.Errorf("key %s is not set", args[0])
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), formatValue(c.Get(args[0])))
+			return nil
+		},
+	}
+}
+
+func configSetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a key in the user configuration file",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := config.SetValue(args[0], args[1]); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s set in %s\n", args[0], config.UserFile())
+			return nil
+		},
+	}
+}
+
+func configUnsetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "unset <key>",
+		Short: "Remove a key from the user configuration file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			removed, err := config.UnsetValue(args[0])
+			if err != nil {
+				return err
+			}
+			if !removed {
+				return fmt.Errorf("key %s is not set in %s", args[0], config.UserFile())
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s removed from %s\n", args[0], config.UserFile())
+			return nil
+		},
+	}
+}
+
+func configListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List every resolved key and value",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := config.Get()
+			for _, key := range c.Keys() {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s = %s\n", key, formatValue(c.Get(key)))
+			}
+			return nil
+		},
+	}
+}
+
+func configPathCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "path",
+		Short: "Print the configuration file locations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "user:    %s%s\n", config.UserFile(), fileState(config.UserFile()))
+			if project := config.ProjectFile(); project != "" {
+				fmt.Fprintf(out, "project: %s\n", project)
+			} else {
+				fmt.Fprintln(out, "project: (none)")
+			}
+			return nil
+		},
+	}
+}
+
+func configValidateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Check that the configuration files parse and are valid",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := config.Read()
+			if err != nil {
+				return err
+			}
+			if err := c.Validate(); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Configuration is valid.")
+			return nil
+		},
+	}
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func fileState(path string) string {
+	if _, err := os.Stat(path); err != nil {
+		return " (missing)"
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rafa-mori/goforge/control"
+	"github.com/spf13/cobra"
+)
+
+func CtlCmd() *cobra.Command {
+	var ctlCmd = &cobra.Command{
+		Use: "ctl",
+		Annotations: GetDescriptions([]string{
+			"Control the running service.",
+			"Talk to the running service through its control socket: list modules, change the log level, toggle debug, reload the configuration or dump goroutine stacks.",
+		}, false),
+	}
+
+	ctlCmd.AddCommand(ctlModulesCommand())
+	ctlCmd.AddCommand(ctlLogLevelCommand())
+	ctlCmd.AddCommand(ctlDebugCommand())
+	ctlCmd.AddCommand(ctlReloadCommand())
+	ctlCmd.AddCommand(ctlStacksCommand())
+
+	return ctlCmd
+}
+
+func ctlModulesCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "modules",
+		Short: "List the modules loaded by the running service",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var list []control.ModuleStatus
+			if err := control.Call("Modules", control.Empty{}, &list); err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "%-20s %-8s %-8s %s\n", "MODULE", "ACTIVE", "MANAGED", "HEALTH")
+			for _, st := range list {
+				fmt.Fprintf(out, "%-20s %-8t %-8t %s\n", st.Name, st.Active, st.Managed, st.Health)
+			}
+			return nil
+		},
+	}
+}
+
+func ctlLogLevelCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "log-level <level>",
+		Short: "Change the log level of the running service",
+		Long:  "Change the log level of the running service. The level may carry per-module levels, e.g. info,db=debug,http=warn; module levels replace the previous ones.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var level string
+			if err := control.Call("SetLogLevel", args[0], &level); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Log level set to %s\n", level)
+			return nil
+		},
+	}
+}
+
+func ctlDebugCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "debug <on|off>",
+		Short: "Toggle debug mode on the running service",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			enabled, err := parseSwitch(args[0])
+			if err != nil {
+				return err
+			}
+			var reply bool
+			if err := control.Call("SetDebug", enabled, &reply); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Debug mode set to %t\n", reply)
+			return nil
+		},
+	}
+}
+
+func ctlReloadCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reload",
+		Short: "Reload the configuration of the running service",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var reply string
+			if err := control.Call("Reload", control.Empty{}, &reply); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), reply)
+			return nil
+		},
+	}
+}
+
+func ctlStacksCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stacks",
+		Short: "Dump the goroutine stacks of the running service",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var stacks string
+			if err := control.Call("Stacks", control.Empty{}, &stacks); err != nil {
+				return err
+			}
+			fmt.Fprint(cmd.OutOrStdout(), stacks)
+			return nil
+		},
+	}
+}
+
+func parseSwitch(s string) (bool, error) {
+	switch s {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("expected on or off, got %q", s)
+	}
+	return enabled, nil
+}
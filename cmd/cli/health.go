@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rafa-mori/goforge/control"
+	"github.com/rafa-mori/goforge/health"
+	"github.com/spf13/cobra"
+)
+
+func HealthCmd() *cobra.Command {
+	var asJSON bool
+	var kind string
+
+	var healthCmd = &cobra.Command{
+		Use: "health",
+		Annotations: GetDescriptions([]string{
+			"Run the health checks.",
+			"Run the registered liveness and readiness checks and print their results. When the service is running, its checks and module states are reported through the control socket. Exits non-zero when a critical check fails.",
+		}, false),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			k, err := health.ParseKind(kind)
+			if err != nil {
+				return err
+			}
+			var report health.Report
+			if serviceRunning() {
+				if err := control.Call("Health", kind, &report); err != nil {
+					return err
+				}
+			} else {
+				report = *health.Run(cmd.Context(), k)
+			}
+
+			out := cmd.OutOrStdout()
+			if asJSON {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(report); err != nil {
+					return err
+				}
+			} else {
+				fmt.Fprintf(out, "Status: %s\n\n", report.Status)
+				fmt.Fprintf(out, "%-20s %-8s %-8s %-10s %s\n", "CHECK", "STATUS", "CRITICAL", "DURATION", "ERROR")
+				for _, res := range report.Checks {
+					fmt.Fprintf(out, "%-20s %-8s %-8t %-10s %s\n", res.Name, res.Status, res.Critical, res.Duration.Round(time.Millisecond), res.Error)
+				}
+			}
+
+			if !report.Healthy() {
+				return errors.New("critical health checks failed")
+			}
+			return nil
+		},
+	}
+
+	healthCmd.Flags().BoolVar(&asJSON, "json", false, "Print the report as JSON")
+	healthCmd.Flags().StringVarP(&kind, "kind", "k", "all", "Checks to run: live, ready or all")
+
+	return healthCmd
+}
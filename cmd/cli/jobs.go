@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rafa-mori/goforge/control"
+	"github.com/rafa-mori/goforge/daemon"
+	"github.com/rafa-mori/goforge/scheduler"
+	"github.com/spf13/cobra"
+)
+
+func JobsCmd() *cobra.Command {
+	var jobsCmd = &cobra.Command{
+		Use: "jobs",
+		Annotations: GetDescriptions([]string{
+			"List and trigger scheduled jobs.",
+			"List the periodic jobs registered by the modules with their next run times, or trigger one manually. When the service is running, its scheduler is used through the control socket.",
+		}, false),
+	}
+
+	jobsCmd.AddCommand(jobsListCommand())
+	jobsCmd.AddCommand(jobsRunCommand())
+
+	return jobsCmd
+}
+
+func jobsListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the registered jobs and their next run times",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var list []scheduler.Status
+			if serviceRunning() {
+				if err := control.Call("Jobs", control.Empty{}, &list); err != nil {
+					return err
+				}
+			} else {
+				list = scheduler.Default.Jobs()
+			}
+
+			out := cmd.OutOrStdout()
+			if len(list) == 0 {
+				fmt.Fprintln(out, "No jobs registered.")
+				return nil
+			}
+			fmt.Fprintf(out, "%-20s %-20s %-25s %-6s %-25s %s\n", "JOB", "SCHEDULE", "NEXT RUN", "RUNS", "LAST RUN", "LAST RESULT")
+			for _, st := range list {
+				fmt.Fprintf(out, "%-20s %-20s %-25s %-6d %-25s %s\n",
+					st.Name, st.Schedule, formatTime(st.Next), st.Runs, formatTime(st.LastRun), lastResult(st))
+			}
+			return nil
+		},
+	}
+}
+
+func jobsRunCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "run <name>",
+		Short: "Run a job now",
+		Long:  "Run a job now. When the service is running, the job is started by its scheduler and the command returns at once; jobs list shows the result. Otherwise the job runs in this process and the command waits for it to finish.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if serviceRunning() {
+				var reply string
+				if err := control.Call("RunJob", args[0], &reply); err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), reply)
+				return nil
+			}
+			if err := scheduler.Default.Run(cmd.Context(), args[0]); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "job %s finished\n", args[0])
+			return nil
+		},
+	}
+}
+
+func serviceRunning() bool {
+	_, err := daemon.Running()
+	return err == nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func lastResult(st scheduler.Status) string {
+	switch {
+	case st.Running:
+		return "running"
+	case st.LastError != "":
+		return "error: " + st.LastError
+	case st.Runs > 0:
+		return "ok (" + st.LastDuration.Round(time.Millisecond).String() + ")"
+	}
+	return "-"
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rafa-mori/goforge/registry"
+	"github.com/spf13/cobra"
+)
+
+func ModulesCmd() *cobra.Command {
+	var modulesCmd = &cobra.Command{
+		Use: "modules",
+		Annotations: GetDescriptions([]string{
+			"Inspect the modules linked into this binary.",
+			"List the registered GoForge modules and inspect their dependency graph.",
+		}, false),
+	}
+
+	modulesCmd.AddCommand(modulesListCommand())
+	modulesCmd.AddCommand(modulesGraphCommand())
+
+	return modulesCmd
+}
+
+func modulesListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the registered modules",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			mods := registry.Modules()
+			if len(mods) == 0 {
+				fmt.Fprintln(out, "No modules registered.")
+				return nil
+			}
+			for _, mod := range mods {
+				state := "inactive"
+				if mod.Active() {
+					state = "active"
+				}
+				line := fmt.Sprintf("%-20s %-8s %s", mod.Module(), state, mod.ShortDescription())
+				if deps := registry.Dependencies(mod); len(deps) > 0 {
+					line += " (depends on: " + strings.Join(deps, ", ") + ")"
+				}
+				fmt.Fprintln(out, strings.TrimRight(line, " "))
+			}
+			return nil
+		},
+	}
+}
+
+func modulesGraphCommand() *cobra.Command {
+	var format string
+
+	var graphCmd = &cobra.Command{
+		Use:   "graph",
+		Short: "Print the module dependency graph",
+		Long:  "Print the dependency graph of the active modules as text or in Graphviz DOT format, in startup order.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			graph := registry.ModuleGraph(registry.ActiveModules())
+			out := cmd.OutOrStdout()
+
+			switch strings.ToLower(format) {
+			case "text":
+				fmt.Fprint(out, graph.String())
+				if order, err := graph.Sort(); err == nil && len(order) > 0 {
+					fmt.Fprintln(out, "\nStartup order: "+strings.Join(order, ", "))
+				}
+			case "dot":
+				fmt.Fprint(out, graph.DOT())
+			default:
+				return fmt.Errorf("unknown format %q, expected text or dot", format)
+			}
+			return graph.Validate()
+		},
+	}
+
+	graphCmd.Flags().StringVarP(&format, "format", "f", "text", "Output format: text or dot")
+
+	return graphCmd
+}
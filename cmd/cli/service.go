@@ -1,35 +1,266 @@
 package cli
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/rafa-mori/goforge"
+	"github.com/rafa-mori/goforge/config"
+	"github.com/rafa-mori/goforge/control"
+	"github.com/rafa-mori/goforge/daemon"
+	"github.com/rafa-mori/goforge/lifecycle"
 	gl "github.com/rafa-mori/goforge/logger"
+	"github.com/rafa-mori/goforge/registry"
+	"github.com/rafa-mori/goforge/scheduler"
+	"github.com/rafa-mori/goforge/server"
+	"github.com/rafa-mori/goforge/systemd"
+	"github.com/rafa-mori/goforge/tasks"
+	vs "github.com/rafa-mori/goforge/version"
 	"github.com/spf13/cobra"
 )
 
 func ServiceCmdList() []*cobra.Command {
 	return []*cobra.Command{
 		startCommand(),
+		stopCommand(),
+		statusCommand(),
+		restartCommand(),
 	}
 }
 
 func startCommand() *cobra.Command {
 	var debug bool
+	var supervise bool
+	var shutdownTimeout time.Duration
 
 	var startCmd = &cobra.Command{
 		Use: "start",
 		Annotations: GetDescriptions([]string{
-			"Start some command.",
-			"This command is used to start the GoForge service with the specified configuration.",
+			"Start the GoForge service.",
+			"Start the GoForge service: run every lifecycle module and serve /healthz, /readyz and /version over HTTP until a termination signal is received.",
 		}, false),
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if debug {
 				gl.SetDebug(true)
 				gl.Log("debug", "Debug mode enabled")
 			}
-			gl.Log("success", "GoForge service started successfully")
+
+			if supervise {
+				release, err := writePidfile()
+				if err != nil {
+					return err
+				}
+				defer release()
+				return superviseSelf(cmd)
+			}
+
+			config.ApplyLogOutput(config.Get())
+			// A supervised instance leaves the pidfile to its supervisor.
+			if !daemon.Supervised() {
+				release, err := writePidfile()
+				if err != nil {
+					return err
+				}
+				defer release()
+			}
+
+			mgr := lifecycle.NewManager(shutdownTimeout)
+			mgr.AddActiveModules()
+			config.OnReload(reloadModules)
+			mgr.Add("config", config.NewWatcher())
+			mgr.Add("control", control.NewServer(mgr, reloadConfig))
+
+			tasks.Default.DependsOn(mgr.Names()...)
+			mgr.Add("tasks", tasks.Default)
+			scheduler.Default.DependsOn(mgr.Names()...)
+			mgr.Add("scheduler", scheduler.Default)
+
+			if cfg := config.Get(); cfg.GetBool(server.ConfigKeyEnabled) {
+				srv := server.New(cfg.GetString(server.ConfigKeyAddr))
+				srv.DependsOn(mgr.Names()...)
+				srv.SetReadinessCheck(mgr.Health)
+				mgr.Add("http", srv)
+			}
+
+			// systemd is told about reloads only between READY and
+			// STOPPING, where they do not change the startup or shutdown
+			// state.
+			var running atomic.Bool
+			config.AroundReload(func() func() {
+				if !running.Load() {
+					return nil
+				}
+				notifySystemd(systemd.StateReloading)
+				return func() { notifySystemd(systemd.StateReady) }
+			})
+			mgr.OnReady(func() {
+				notifySystemd(systemd.StateReady)
+				running.Store(true)
+				gl.Log("success", "GoForge service started successfully")
+			})
+			mgr.OnStopping(func() {
+				running.Store(false)
+				notifySystemd(systemd.StateStopping)
+			})
+
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+			go systemd.RunWatchdog(ctx, mgr.Healthy)
+
+			if err := mgr.Run(ctx); err != nil {
+				return err
+			}
+			gl.Log("success", "GoForge service stopped")
+			return nil
 		},
 	}
 
 	startCmd.Flags().BoolVarP(&debug, "debug", "d", false, "Enable debug mode")
+	startCmd.Flags().DurationVar(&shutdownTimeout, "shutdown-timeout", lifecycle.DefaultShutdownTimeout, "Deadline for stopping all modules")
+	startCmd.Flags().BoolVar(&supervise, "supervise", false, "Run the service as a child process and restart it when it crashes")
+	// The restart policy flags only matter with --supervise; the child
+	// accepts and ignores them.
+	addSupervisorFlags(startCmd)
+	startCmd.Flags().String("addr", server.DefaultAddr, "Address the HTTP server listens on")
+	startCmd.Flags().Bool("http", true, "Serve the HTTP health and version endpoints")
+	startCmd.Flags().String("log-dir", "", "Write logs to a file in this directory instead of stdout")
+	startCmd.Flags().String("log-file", "", "Log file name, relative to --log-dir, or an absolute path")
+	startCmd.Flags().Int("log-max-size", 100, "Rotate the log file when it would grow past this many MiB (0 disables)")
+	startCmd.Flags().Duration("log-max-age", 24*time.Hour, "Rotate the log file after this long (0 disables)")
+	startCmd.Flags().Int("log-max-backups", 7, "Rotated log files to keep (0 keeps all)")
+	startCmd.Flags().Bool("log-compress", true, "Gzip rotated log files")
+	config.BindFlag(server.ConfigKeyAddr, startCmd.Flags().Lookup("addr"))
+	config.BindFlag(config.KeyLogDir, startCmd.Flags().Lookup("log-dir"))
+	config.BindFlag(config.KeyLogFile, startCmd.Flags().Lookup("log-file"))
+	config.BindFlag(config.KeyLogMaxSizeMB, startCmd.Flags().Lookup("log-max-size"))
+	config.BindFlag(config.KeyLogMaxAge, startCmd.Flags().Lookup("log-max-age"))
+	config.BindFlag(config.KeyLogMaxBackups, startCmd.Flags().Lookup("log-max-backups"))
+	config.BindFlag(config.KeyLogCompress, startCmd.Flags().Lookup("log-compress"))
+	config.BindFlag(server.ConfigKeyEnabled, startCmd.Flags().Lookup("http"))
 
 	return startCmd
 }
+
+func stopCommand() *cobra.Command {
+	var timeout time.Duration
+	var force bool
+
+	var stopCmd = &cobra.Command{
+		Use: "stop",
+		Annotations: GetDescriptions([]string{
+			"Stop the running GoForge service.",
+			"Send SIGTERM to the running GoForge service and wait for it to exit.",
+		}, false),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info, err := daemon.Stop(timeout, force)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Stopped pid %d after %s of uptime\n", info.PID, info.Uptime())
+			return nil
+		},
+	}
+
+	stopCmd.Flags().DurationVarP(&timeout, "timeout", "t", 30*time.Second, "How long to wait for the service to exit")
+	stopCmd.Flags().BoolVarP(&force, "force", "f", false, "Kill the service if it does not exit in time")
+
+	return stopCmd
+}
+
+func statusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use: "status",
+		Annotations: GetDescriptions([]string{
+			"Show whether the GoForge service is running.",
+			"Show the pid, version and uptime of the running GoForge service. Exits non-zero when it is not running.",
+		}, false),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info, err := daemon.Running()
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Status:  running\n")
+			fmt.Fprintf(out, "PID:     %d\n", info.PID)
+			fmt.Fprintf(out, "Version: %s\n", info.Version)
+			fmt.Fprintf(out, "Started: %s\n", info.StartedAt.Format(time.RFC3339))
+			fmt.Fprintf(out, "Uptime:  %s\n", info.Uptime())
+			return nil
+		},
+	}
+}
+
+func restartCommand() *cobra.Command {
+	var timeout time.Duration
+
+	var restartCmd = &cobra.Command{
+		Use: "restart",
+		Annotations: GetDescriptions([]string{
+			"Restart the GoForge service.",
+			"Stop the running GoForge service and start it again in the background with the same arguments.",
+		}, false),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info, err := daemon.Restart(timeout, []string{"start"})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Started pid %d (version %s)\n", info.PID, info.Version)
+			return nil
+		},
+	}
+
+	restartCmd.Flags().DurationVarP(&timeout, "timeout", "t", 30*time.Second, "How long to wait for the service to stop and start")
+
+	return restartCmd
+}
+
+// writePidfile records the current process in the pidfile. release
+// removes it, and runs on forced exits too.
+func writePidfile() (release func(), err error) {
+	if _, err := daemon.WritePidfile(vs.GetVersion()); err != nil {
+		return nil, err
+	}
+	removeHook := daemon.AtExit(func() { _ = daemon.RemovePidfile() })
+	return func() {
+		removeHook()
+		if err := daemon.RemovePidfile(); err != nil {
+			gl.Log("warn", "Failed to remove pidfile: "+err.Error())
+		}
+	}, nil
+}
+
+func notifySystemd(state string) {
+	if sent, err := systemd.Notify(state); err != nil {
+		gl.Log("warn", "Failed to notify systemd: "+err.Error())
+	} else if sent {
+		gl.Log("debug", "Notified systemd: "+state)
+	}
+}
+
+func reloadConfig() error {
+	_, err := config.Reload()
+	return err
+}
+
+// reloadModules hands every active goforge.Reloadable module its
+// configuration section before and after a reload.
+func reloadModules(prev, next *config.Config) error {
+	var errs []error
+	for _, mod := range registry.ActiveModules() {
+		r, ok := mod.(goforge.Reloadable)
+		if !ok {
+			continue
+		}
+		name := mod.Module()
+		if err := r.Reload(prev.Section(name), next.Section(name)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
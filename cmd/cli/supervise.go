@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/rafa-mori/goforge/config"
+	"github.com/rafa-mori/goforge/daemon"
+	"github.com/rafa-mori/goforge/supervisor"
+	"github.com/spf13/cobra"
+)
+
+func SuperviseCmd() *cobra.Command {
+	var superviseCmd = &cobra.Command{
+		Use: "supervise [flags] -- <command> [args...]",
+		Annotations: GetDescriptions([]string{
+			"Run a command and restart it when it crashes.",
+			"Run a command as a child process, restart it on crash with exponential backoff, give up after too many restarts within a window and forward signals to it.",
+		}, false),
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := exec.LookPath(args[0])
+			if err != nil {
+				return err
+			}
+			return supervisor.New(path, args[1:], supervisor.OptionsFromConfig()).Run(cmd.Context())
+		},
+	}
+
+	addSupervisorFlags(superviseCmd)
+
+	return superviseCmd
+}
+
+func addSupervisorFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.Int("max-restarts", 5, "Crashes tolerated within the restart window before giving up")
+	flags.Duration("restart-window", 0, "Window in which crashes are counted (default 5m)")
+	flags.Duration("initial-backoff", 0, "Delay before the first restart, doubled on every crash (default 1s)")
+	flags.Duration("max-backoff", 0, "Upper bound of the restart delay (default 1m)")
+	flags.Duration("stop-timeout", 0, "How long the child may take to exit before it is killed (default 30s)")
+
+	config.BindFlag(supervisor.ConfigKeyMaxRestarts, flags.Lookup("max-restarts"))
+	config.BindFlag(supervisor.ConfigKeyWindow, flags.Lookup("restart-window"))
+	config.BindFlag(supervisor.ConfigKeyInitialBackoff, flags.Lookup("initial-backoff"))
+	config.BindFlag(supervisor.ConfigKeyMaxBackoff, flags.Lookup("max-backoff"))
+	config.BindFlag(supervisor.ConfigKeyStopTimeout, flags.Lookup("stop-timeout"))
+}
+
+// superviseSelf runs the current executable with the current arguments,
+// minus the --supervise flag, under the supervisor. The child is marked as
+// supervised so that it leaves the pidfile, which records the arguments
+// restart uses, to this process.
+func superviseSelf(cmd *cobra.Command) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err := os.Setenv(daemon.SupervisedEnv(), "1"); err != nil {
+		return err
+	}
+	args := make([]string, 0, len(os.Args)-1)
+	for _, arg := range os.Args[1:] {
+		if arg == "--supervise" || strings.HasPrefix(arg, "--supervise=") {
+			continue
+		}
+		args = append(args, arg)
+	}
+	return supervisor.New(exe, args, supervisor.OptionsFromConfig()).Run(cmd.Context())
+}
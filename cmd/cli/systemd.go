@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/rafa-mori/goforge/systemd"
+	"github.com/spf13/cobra"
+)
+
+func SystemdCmd() *cobra.Command {
+	var user bool
+
+	var serviceCmd = &cobra.Command{
+		Use: "service",
+		Annotations: GetDescriptions([]string{
+			"Manage the systemd unit of the service.",
+			"Install, uninstall, enable and disable the systemd unit rendered from the manifest. Use --user for a user unit instead of a system unit.",
+		}, false),
+	}
+
+	serviceCmd.PersistentFlags().BoolVar(&user, "user", false, "Manage a user unit instead of a system unit")
+
+	serviceCmd.AddCommand(serviceInstallCommand(&user))
+	serviceCmd.AddCommand(serviceUninstallCommand(&user))
+	serviceCmd.AddCommand(serviceEnableCommand(&user))
+	serviceCmd.AddCommand(serviceDisableCommand(&user))
+
+	return serviceCmd
+}
+
+func serviceInstallCommand(user *bool) *cobra.Command {
+	var printOnly bool
+
+	var installCmd = &cobra.Command{
+		Use:   "install",
+		Short: "Write the unit file and reload systemd",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if printOnly {
+				unit, err := systemd.NewUnit(*user)
+				if err != nil {
+					return err
+				}
+				content, err := unit.Render()
+				if err != nil {
+					return err
+				}
+				fmt.Fprint(cmd.OutOrStdout(), content)
+				return nil
+			}
+			path, err := systemd.Install(*user)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Installed %s\n", path)
+			return nil
+		},
+	}
+
+	installCmd.Flags().BoolVar(&printOnly, "print", false, "Print the unit instead of installing it")
+
+	return installCmd
+}
+
+func serviceUninstallCommand(user *bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   "uninstall",
+		Short: "Stop, disable and remove the unit file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := systemd.Uninstall(*user)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed %s\n", path)
+			return nil
+		},
+	}
+}
+
+func serviceEnableCommand(user *bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   "enable",
+		Short: "Enable the unit and start it now",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := systemd.Enable(*user); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Enabled %s\n", systemd.UnitName())
+			return nil
+		},
+	}
+}
+
+func serviceDisableCommand(user *bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   "disable",
+		Short: "Stop the unit and disable it",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := systemd.Disable(*user); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Disabled %s\n", systemd.UnitName())
+			return nil
+		},
+	}
+}
@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rafa-mori/goforge/control"
+	"github.com/rafa-mori/goforge/tasks"
+	"github.com/spf13/cobra"
+)
+
+func TasksCmd() *cobra.Command {
+	var tasksCmd = &cobra.Command{
+		Use: "tasks",
+		Annotations: GetDescriptions([]string{
+			"Inspect the background task queue.",
+			"List, retry and purge the tasks of the on-disk background task queue. When the service is running, changes go through its control socket.",
+		}, false),
+	}
+
+	tasksCmd.AddCommand(tasksListCommand())
+	tasksCmd.AddCommand(tasksRetryCommand())
+	tasksCmd.AddCommand(tasksPurgeCommand())
+
+	return tasksCmd
+}
+
+func tasksListCommand() *cobra.Command {
+	var states []string
+
+	var lsCmd = &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List the queued tasks",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter, err := parseStates(states)
+			if err != nil {
+				return err
+			}
+			list, err := tasks.Read(tasks.DefaultPath(), filter...)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if len(list) == 0 {
+				fmt.Fprintln(out, "No tasks queued.")
+				return nil
+			}
+			fmt.Fprintf(out, "%-16s %-20s %-8s %-8s %-25s %s\n", "ID", "TYPE", "STATE", "ATTEMPTS", "NEXT ATTEMPT", "LAST ERROR")
+			for _, t := range list {
+				next := "-"
+				if t.State == tasks.StatePending {
+					next = formatTime(t.NextAttemptAt)
+				}
+				fmt.Fprintf(out, "%-16s %-20s %-8s %-8d %-25s %s\n", t.ID, t.Type, t.State, t.Attempts, next, t.LastError)
+			}
+			return nil
+		},
+	}
+
+	lsCmd.Flags().StringSliceVarP(&states, "state", "s", nil, "Only list tasks in these states (pending, running, dead)")
+
+	return lsCmd
+}
+
+func tasksRetryCommand() *cobra.Command {
+	var all bool
+
+	var retryCmd = &cobra.Command{
+		Use:   "retry [<id>...]",
+		Short: "Move dead tasks back to the queue",
+		Long:  "Make the given dead-lettered tasks, or every dead task with --all, pending again with a fresh attempt count.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && !all {
+				return fmt.Errorf("give the ids of the tasks to retry, or --all")
+			}
+			var retried []string
+			var err error
+			if serviceRunning() {
+				err = control.Call("RetryTasks", args, &retried)
+			} else {
+				retried, err = tasks.Default.Retry(args...)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Retried %d tasks\n", len(retried))
+			return err
+		},
+	}
+
+	retryCmd.Flags().BoolVar(&all, "all", false, "Retry every dead task")
+
+	return retryCmd
+}
+
+func tasksPurgeCommand() *cobra.Command {
+	var states []string
+	var all bool
+
+	var purgeCmd = &cobra.Command{
+		Use:   "purge [<id>...]",
+		Short: "Remove tasks from the queue",
+		Long:  "Remove the given tasks, or with --all every task in the states selected with --state (dead by default). Running tasks are never purged.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && !all {
+				return fmt.Errorf("give the ids of the tasks to purge, or --all")
+			}
+			if len(args) > 0 && all {
+				return fmt.Errorf("give either task ids or --all, not both")
+			}
+			filter, err := parseStates(states)
+			if err != nil {
+				return err
+			}
+			var purged []string
+			if serviceRunning() {
+				err = control.Call("PurgeTasks", control.PurgeArgs{IDs: args, States: filter}, &purged)
+			} else {
+				purged, err = tasks.Default.Purge(args, filter...)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Purged %d tasks\n", len(purged))
+			return err
+		},
+	}
+
+	purgeCmd.Flags().BoolVar(&all, "all", false, "Purge every task in the states selected with --state")
+	purgeCmd.Flags().StringSliceVarP(&states, "state", "s", []string{string(tasks.StateDead)}, "States of the tasks purged by --all (pending, dead)")
+
+	return purgeCmd
+}
+
+func parseStates(names []string) ([]tasks.State, error) {
+	states := make([]tasks.State, 0, len(names))
+	for _, name := range names {
+		switch s := tasks.State(strings.ToLower(name)); s {
+		case tasks.StatePending, tasks.StateRunning, tasks.StateDead:
+			states = append(states, s)
+		default:
+			return nil, fmt.Errorf("unknown task state %q", name)
+		}
+	}
+	return states, nil
+}
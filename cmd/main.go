@@ -2,16 +2,96 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rafa-mori/goforge/daemon"
 	gl "github.com/rafa-mori/goforge/logger"
+	"github.com/rafa-mori/goforge/plugins"
 )
 
 // This file is the entry point for the GoForge CLI application.
 // It initializes the logger and starts the application by executing the main command.
 // It allows the application to be run as a standalone CLI tool.
 
+const (
+	// forceExitCode is the exit status used when a second signal forces
+	// the exit.
+	forceExitCode = 130
+	// duplicateWindow is how soon after the first signal a second one is
+	// ignored.
+	duplicateWindow = 50 * time.Millisecond
+)
+
 // main initializes the logger and creates a new GoBE instance.
 func main() {
-	if err := RegX().Command().Execute(); err != nil {
-		gl.Log("fatal", err.Error())
+	ctx, cancel := signalContext()
+	defer cancel()
+	defer closeLog()
+
+	if err := RegX().ExecuteContext(ctx); err != nil {
+		cancel()
+		// A fatal line exits inside the logger, skipping deferred calls,
+		// so the log output is flushed and closed before it is written.
+		closeLog()
+		code := 1
+		// Plugins report their own failures; only their status is kept.
+		var exitErr *plugins.ExitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.Code
+		} else {
+			gl.Log("fatal", err.Error())
+		}
+		// The fatal message may be filtered by the log level; the exit
+		// status must still report the failure.
+		os.Exit(code)
+	}
+}
+
+// closeLog writes out buffered log lines and closes the log file. Later
+// lines go synchronously to stdout.
+func closeLog() {
+	_ = gl.SetAsync(gl.AsyncOptions{})
+	_ = gl.CloseFile()
+}
+
+// signalContext returns a context cancelled on the first SIGINT or SIGTERM.
+// A second signal exits the process immediately, after the cleanup
+// registered with daemon.AtExit. A signal arriving within duplicateWindow of
+// the first one is taken as the same signal delivered twice, as happens
+// when it is sent to both the process and its process group.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		var first time.Time
+		select {
+		case sig := <-sigs:
+			first = time.Now()
+			gl.Log("warn", "Received "+sig.String()+", cancelling (send again to force exit)")
+			cancel()
+		case <-ctx.Done():
+			return
+		}
+		for sig := range sigs {
+			if time.Since(first) < duplicateWindow {
+				continue
+			}
+			gl.Log("error", "Received "+sig.String()+" again, forcing exit")
+			daemon.RunExitHooks()
+			closeLog()
+			os.Exit(forceExitCode)
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigs)
+		cancel()
 	}
 }
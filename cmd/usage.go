@@ -48,6 +48,15 @@ func hasModuleCommands(cmds []*cobra.Command) bool {
 	return false
 }
 
+func hasPluginCommands(cmds []*cobra.Command) bool {
+	for _, cmd := range cmds {
+		if cmd.Annotations["plugin"] != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func setUsageDefinition(cmd *cobra.Command) {
 	cobra.AddTemplateFunc("colorYellow", colorYellow)
 	cobra.AddTemplateFunc("colorGreen", colorGreen)
@@ -56,6 +65,7 @@ func setUsageDefinition(cmd *cobra.Command) {
 	cobra.AddTemplateFunc("colorHelp", colorHelp)
 	cobra.AddTemplateFunc("hasServiceCommands", hasServiceCommands)
 	cobra.AddTemplateFunc("hasModuleCommands", hasModuleCommands)
+	cobra.AddTemplateFunc("hasPluginCommands", hasPluginCommands)
 
 	// Altera o template de uso do cobra
 	cmd.SetUsageTemplate(cliUsageTemplate)
@@ -74,8 +84,11 @@ var cliUsageTemplate = `{{- if index .Annotations "banner" }}{{colorBlue (index
 
 {{colorYellow "Example:"}}
   {{.Example}}{{end}}{{if .HasAvailableSubCommands}}
-{{colorYellow "Available Commands:"}}{{range .Commands}}{{if (or .IsAvailableCommand (eq .Name "help"))}}
-  {{colorGreen (rpad .Name .NamePadding) }} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}
+{{colorYellow "Available Commands:"}}{{range .Commands}}{{if and (or .IsAvailableCommand (eq .Name "help")) (not (index .Annotations "plugin"))}}
+  {{colorGreen (rpad .Name .NamePadding) }} {{.Short}}{{end}}{{end}}{{if hasPluginCommands .Commands}}
+
+{{colorYellow "Plugin Commands:"}}{{range .Commands}}{{if and .IsAvailableCommand (index .Annotations "plugin")}}
+  {{colorGreen (rpad .Name .NamePadding) }} {{.Short}}{{end}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}
 
 {{colorYellow "Flags:"}}
 {{.LocalFlags.FlagUsages | trimTrailingWhitespaces | colorHelp}}{{end}}{{if .HasAvailableInheritedFlags}}
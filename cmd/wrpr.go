@@ -1,18 +1,26 @@
 package main
 
 import (
+	"context"
+
+	"github.com/rafa-mori/goforge"
 	cc "github.com/rafa-mori/goforge/cmd/cli"
+	"github.com/rafa-mori/goforge/config"
 	gl "github.com/rafa-mori/goforge/logger"
+	"github.com/rafa-mori/goforge/plugins"
+	"github.com/rafa-mori/goforge/registry"
 	vs "github.com/rafa-mori/goforge/version"
 	"github.com/spf13/cobra"
 
 	"os"
 	"strings"
+	"sync"
 )
 
 type GoForge struct {
 	parentCmdName string
 	printBanner   bool
+	configFile    string
 }
 
 func (m *GoForge) Alias() string {
@@ -41,6 +49,9 @@ func (m *GoForge) Module() string {
 func (m *GoForge) Execute() error {
 	return m.Command().Execute()
 }
+func (m *GoForge) ExecuteContext(ctx context.Context) error {
+	return m.Command().ExecuteContext(ctx)
+}
 func (m *GoForge) Command() *cobra.Command {
 	gl.Log("debug", "Starting GoForge CLI...")
 
@@ -53,10 +64,32 @@ func (m *GoForge) Command() *cobra.Command {
 			m.LongDescription(),
 			m.ShortDescription(),
 		}, m.printBanner),
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			// Arguments are already validated here, so errors from now on
+			// are runtime failures that the usage text would only clutter.
+			cmd.SilenceUsage = true
+			m.loadConfig()
+		},
 	}
 
+	rtCmd.PersistentFlags().StringVar(&m.configFile, "config", "", "Path of the user configuration file")
+	rtCmd.PersistentFlags().String("log-format", "text", "Log output format: text, json or logfmt")
+	config.BindFlag(config.KeyLogFormat, rtCmd.PersistentFlags().Lookup("log-format"))
+	rtCmd.PersistentFlags().String("log-level", "", "Log level, optionally with per-module levels: info,db=debug,http=warn")
+	config.BindFlag(config.KeyLogLevel, rtCmd.PersistentFlags().Lookup("log-level"))
+
 	rtCmd.AddCommand(cc.ServiceCmdList()...)
+	rtCmd.AddCommand(cc.ConfigCmd())
+	rtCmd.AddCommand(cc.SystemdCmd())
+	rtCmd.AddCommand(cc.SuperviseCmd())
+	rtCmd.AddCommand(cc.CtlCmd())
+	rtCmd.AddCommand(cc.JobsCmd())
+	rtCmd.AddCommand(cc.TasksCmd())
+	rtCmd.AddCommand(cc.HealthCmd())
+	rtCmd.AddCommand(cc.ModulesCmd())
 	rtCmd.AddCommand(vs.CliCommand())
+	m.mountModules(rtCmd)
+	m.mountPlugins(rtCmd)
 
 	// Set usage definitions for the command and its subcommands
 	setUsageDefinition(rtCmd)
@@ -71,6 +104,130 @@ func (m *GoForge) Command() *cobra.Command {
 
 	return rtCmd
 }
+func (m *GoForge) loadConfig() {
+	if m.configFile != "" {
+		config.SetUserFile(m.configFile)
+	}
+	c, err := config.Load()
+	if err != nil {
+		gl.Log("warn", "Failed to load configuration: "+err.Error())
+		c = config.Get()
+	}
+	config.ApplyLogging(c)
+}
+func (m *GoForge) mountModules(rtCmd *cobra.Command) {
+	for _, mod := range registry.ActiveModules() {
+		if modCmd := m.mountModule(rtCmd, mod); modCmd != nil {
+			gl.Log("debug", "Module "+mod.Module()+" mounted as "+modCmd.Name())
+		}
+	}
+}
+func (m *GoForge) mountPlugins(rtCmd *cobra.Command) {
+	found := plugins.Discover()
+	mounted := make(map[*plugins.Plugin]*cobra.Command, len(found))
+	for _, plg := range found {
+		if plgCmd := m.mountModule(rtCmd, plg); plgCmd != nil {
+			mounted[plg] = plgCmd
+			gl.Log("debug", "Plugin "+plg.Path+" mounted as "+plgCmd.Name())
+		}
+	}
+	if len(mounted) == 0 {
+		return
+	}
+
+	// The metadata handshake runs plugin executables, so it is deferred
+	// until help or usage text needs the descriptions.
+	var once sync.Once
+	describe := func() {
+		once.Do(func() {
+			plugins.LoadAll(found)
+			for plg, plgCmd := range mounted {
+				m.describePlugin(plgCmd, plg)
+			}
+		})
+	}
+	// Aliases of plugins with cached metadata are mounted above. An unknown
+	// command may still be the alias of a plugin not handshaken yet.
+	if len(os.Args) > 1 {
+		if cmd, _, err := rtCmd.Find(os.Args[1:]); err != nil || cmd == rtCmd {
+			describe()
+		}
+	}
+	helpFunc, usageFunc := rtCmd.HelpFunc(), rtCmd.UsageFunc()
+	rtCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		describe()
+		helpFunc(cmd, args)
+	})
+	rtCmd.SetUsageFunc(func(cmd *cobra.Command) error {
+		describe()
+		return usageFunc(cmd)
+	})
+}
+
+// describePlugin refreshes the descriptive fields of a plugin command once
+// the plugin metadata is loaded.
+func (m *GoForge) describePlugin(plgCmd *cobra.Command, plg *plugins.Plugin) {
+	if usage := plg.Usage(); usage != "" {
+		plgCmd.Use = plg.Name + " " + usage
+	}
+	if alias := plg.Alias(); alias != "" && alias != plgCmd.Name() && !plgCmd.HasAlias(alias) {
+		plgCmd.Aliases = append(plgCmd.Aliases, alias)
+	}
+	plgCmd.Short = plg.ShortDescription()
+	plgCmd.Long = plg.LongDescription()
+	plgCmd.Example = moduleExamples(plg)
+	for k, v := range cc.GetDescriptions([]string{
+		plg.LongDescription(),
+		plg.ShortDescription(),
+	}, m.printBanner) {
+		plgCmd.Annotations[k] = v
+	}
+	plgCmd.Annotations["module"] = plg.Module()
+}
+func (m *GoForge) mountModule(rtCmd *cobra.Command, mod goforge.GoForge) *cobra.Command {
+	modCmd := mod.Command()
+	if modCmd == nil {
+		gl.Log("warn", "Module "+mod.Module()+" has no command, skipping")
+		return nil
+	}
+	if modCmd.Use == "" {
+		modCmd.Use = mod.Module()
+	}
+	if existing, _, err := rtCmd.Find([]string{modCmd.Name()}); err == nil && existing != rtCmd {
+		gl.Log("warn", "Command "+modCmd.Name()+" from module "+mod.Module()+" conflicts with an existing command, skipping")
+		return nil
+	}
+	if alias := mod.Alias(); alias != "" && alias != modCmd.Name() && !modCmd.HasAlias(alias) {
+		modCmd.Aliases = append(modCmd.Aliases, alias)
+	}
+	if modCmd.Short == "" {
+		modCmd.Short = mod.ShortDescription()
+	}
+	if modCmd.Long == "" {
+		modCmd.Long = mod.LongDescription()
+	}
+	if modCmd.Example == "" {
+		modCmd.Example = moduleExamples(mod)
+	}
+	if modCmd.Annotations == nil {
+		modCmd.Annotations = make(map[string]string)
+	}
+	for k, v := range cc.GetDescriptions([]string{
+		mod.LongDescription(),
+		mod.ShortDescription(),
+	}, m.printBanner) {
+		if _, exists := modCmd.Annotations[k]; !exists {
+			modCmd.Annotations[k] = v
+		}
+	}
+	modCmd.Annotations["module"] = mod.Module()
+
+	rtCmd.AddCommand(modCmd)
+	return modCmd
+}
+func moduleExamples(mod goforge.GoForge) string {
+	return strings.Join(mod.Examples(), "\n  ")
+}
 func (m *GoForge) SetParentCmdName(rtCmd string) {
 	m.parentCmdName = rtCmd
 }
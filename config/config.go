@@ -0,0 +1,322 @@
+// Package config provides the layered configuration of the application.
+//
+// Values are resolved with the following precedence, highest first:
+//
+//  1. command line flags bound with BindFlag
+//  2. environment variables (<BIN>_SECTION_KEY, e.g. GOFORGE_LOG_LEVEL)
+//  3. the user file ($XDG_CONFIG_HOME/<bin>/config.yaml)
+//  4. the project file (.<bin>.yaml in the working directory or a parent)
+//  5. defaults taken from the embedded manifest
+//
+// Modules read and write their own namespaced section through Module.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	manifest "github.com/rafa-mori/goforge/info"
+	"github.com/rafa-mori/goforge/paths"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// ModulesKey is the top-level key holding the per-module sections.
+const ModulesKey = "modules"
+
+// Validator checks a loaded configuration.
+type Validator func(*Config) error
+
+// Config is one immutable snapshot of the merged configuration layers.
+type Config struct {
+	v           *viper.Viper
+	userFile    string
+	projectFile string
+	loadedAt    time.Time
+}
+
+var (
+	mu         sync.RWMutex
+	current    *Config
+	userFile   string
+	flagBinds  = make(map[string][]*pflag.Flag)
+	defaults   = make(map[string]any)
+	validators []Validator
+)
+
+// SetUserFile overrides the path of the user configuration file. An empty
+// path restores the default location.
+func SetUserFile(path string) {
+	mu.Lock()
+	defer mu.Unlock()
+	userFile = path
+}
+
+// UserFile returns the path of the user configuration file.
+func UserFile() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if userFile != "" {
+		return userFile
+	}
+	if path := os.Getenv(EnvPrefix() + "_CONFIG"); path != "" {
+		return path
+	}
+	return filepath.Join(paths.ConfigDir(), "config.yaml")
+}
+
+// ProjectFile returns the path of the nearest project configuration file,
+// searching from the working directory up to the filesystem root. It
+// returns "" when there is none.
+func ProjectFile() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	name := "." + paths.AppName() + ".yaml"
+	for {
+		candidate := filepath.Join(dir, name)
+		if stat, err := os.Stat(candidate); err == nil && stat.Mode().IsRegular() {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// EnvPrefix returns the prefix of the environment variables read by the
+// configuration, derived from the manifest bin.
+func EnvPrefix() string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(paths.AppName()))
+}
+
+// BindFlag binds a command line flag to key. The flag only takes
+// precedence when it was set explicitly. A key may be bound to the flags of
+// several commands; the one set on the command line is used.
+func BindFlag(key string, flag *pflag.Flag) {
+	if flag == nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	flagBinds[key] = append(flagBinds[key], flag)
+	if current != nil {
+		_ = current.v.BindPFlag(key, boundFlag(flagBinds[key]))
+	}
+}
+
+// boundFlag returns the flag of flags that was set, or the first one.
+func boundFlag(flags []*pflag.Flag) *pflag.Flag {
+	for _, flag := range flags {
+		if flag.Changed {
+			return flag
+		}
+	}
+	return flags[0]
+}
+
+// bindFlags binds the registered flags to v.
+func bindFlags(v *viper.Viper) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for key, flags := range flagBinds {
+		_ = v.BindPFlag(key, boundFlag(flags))
+	}
+}
+
+// SetDefault registers the default value of key, below every other layer.
+func SetDefault(key string, value any) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaults[key] = value
+	if current != nil {
+		current.v.SetDefault(key, value)
+	}
+}
+
+// AddValidator registers a validator run by Validate and by every Load.
+func AddValidator(fn Validator) {
+	mu.Lock()
+	defer mu.Unlock()
+	validators = append(validators, fn)
+}
+
+// Load reads every layer into a new Config, validates it and makes it the
+// current configuration.
+func Load() (*Config, error) {
+	c, err := Read()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	current = c
+	mu.Unlock()
+	return c, nil
+}
+
+// Read reads every layer into a new Config without validating it or
+// replacing the current configuration.
+func Read() (*Config, error) {
+	c := &Config{
+		v:           viper.New(),
+		userFile:    UserFile(),
+		projectFile: ProjectFile(),
+		loadedAt:    time.Now(),
+	}
+	c.v.SetConfigType("yaml")
+	c.v.SetEnvPrefix(EnvPrefix())
+	c.v.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	c.v.AutomaticEnv()
+
+	setDefaults(c.v)
+
+	for _, file := range []string{c.projectFile, c.userFile} {
+		if err := mergeFile(c.v, file); err != nil {
+			return nil, err
+		}
+	}
+
+	bindFlags(c.v)
+
+	return c, nil
+}
+
+// Get returns the current configuration, loading it on first use. When the
+// files cannot be read or are invalid, only the defaults, environment and
+// flags are used; that configuration is not kept, so the next call tries
+// the files again.
+func Get() *Config {
+	mu.RLock()
+	c := current
+	mu.RUnlock()
+	if c != nil {
+		return c
+	}
+	c, err := Load()
+	if err != nil {
+		c = &Config{v: viper.New(), userFile: UserFile(), loadedAt: time.Now()}
+		c.v.SetEnvPrefix(EnvPrefix())
+		c.v.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+		c.v.AutomaticEnv()
+		setDefaults(c.v)
+		bindFlags(c.v)
+	}
+	return c
+}
+
+func setDefaults(v *viper.Viper) {
+	mu.RLock()
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+	mu.RUnlock()
+
+	info, err := manifest.GetManifest()
+	if err != nil {
+		return
+	}
+	v.SetDefault("app.name", info.GetName())
+	v.SetDefault("app.bin", info.GetBin())
+	v.SetDefault("app.version", info.GetVersion())
+	v.SetDefault("app.repository", info.GetRepository())
+	if lvl := info.GetLogLevel(); lvl != "" {
+		v.SetDefault(KeyLogLevel, lvl)
+	}
+	if info.IsDebug() {
+		v.SetDefault("log.debug", true)
+	}
+	if info.IsShowTrace() {
+		v.SetDefault("log.trace", true)
+	}
+	if dir := info.GetLogDir(); dir != "" {
+		v.SetDefault(KeyLogDir, dir)
+	}
+	if file := info.GetLogFile(); file != "" {
+		v.SetDefault(KeyLogFile, file)
+	}
+	if size := info.GetLogMaxSizeMB(); size > 0 {
+		v.SetDefault(KeyLogMaxSizeMB, size)
+	}
+	if age := info.GetLogMaxAge(); age != "" {
+		v.SetDefault(KeyLogMaxAge, age)
+	}
+	if backups := info.GetLogMaxBackups(); backups > 0 {
+		v.SetDefault(KeyLogMaxBackups, backups)
+	}
+	v.SetDefault(KeyLogCompress, info.IsLogCompress())
+}
+
+func mergeFile(v *viper.Viper, file string) error {
+	if file == "" {
+		return nil
+	}
+	data, err := os.Open(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer func() { _ = data.Close() }()
+	if err := v.MergeConfig(data); err != nil {
+		return fmt.Errorf("config file %s: %w", file, err)
+	}
+	return nil
+}
+
+// Validate runs every registered validator against c.
+func (c *Config) Validate() error {
+	mu.RLock()
+	list := append([]Validator(nil), validators...)
+	mu.RUnlock()
+
+	var errs []error
+	for _, fn := range list {
+		if err := fn(c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// UserFile returns the user file this configuration was read from.
+func (c *Config) UserFile() string { return c.userFile }
+
+// ProjectFile returns the project file this configuration was read from,
+// or "" when there was none.
+func (c *Config) ProjectFile() string { return c.projectFile }
+
+// LoadedAt returns when the configuration was read.
+func (c *Config) LoadedAt() time.Time { return c.loadedAt }
+
+func (c *Config) Get(key string) any                   { return c.v.Get(key) }
+func (c *Config) GetString(key string) string          { return c.v.GetString(key) }
+func (c *Config) GetBool(key string) bool              { return c.v.GetBool(key) }
+func (c *Config) GetInt(key string) int                { return c.v.GetInt(key) }
+func (c *Config) GetFloat64(key string) float64        { return c.v.GetFloat64(key) }
+func (c *Config) GetDuration(key string) time.Duration { return c.v.GetDuration(key) }
+func (c *Config) GetStringSlice(key string) []string   { return c.v.GetStringSlice(key) }
+func (c *Config) IsSet(key string) bool                { return c.v.IsSet(key) }
+func (c *Config) Unmarshal(key string, out any) error  { return c.v.UnmarshalKey(key, out) }
+
+// Keys returns every known key, sorted.
+func (c *Config) Keys() []string {
+	keys := c.v.AllKeys()
+	sort.Strings(keys)
+	return keys
+}
+
+// Settings returns the merged configuration as a nested map.
+func (c *Config) Settings() map[string]any { return c.v.AllSettings() }
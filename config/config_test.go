@@ -0,0 +1,193 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+// withFiles points the configuration at a fresh user file and a project
+// file in a fresh working directory, with the given contents ("" leaves the
+// file out).
+func withFiles(t *testing.T, user, project string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	userPath := filepath.Join(dir, "user.yaml")
+	if user != "" {
+		writeFile(t, userPath, user)
+	}
+	if project != "" {
+		writeFile(t, filepath.Join(dir, ".goforge.yaml"), project)
+	}
+	SetUserFile(userPath)
+	t.Cleanup(func() {
+		SetUserFile("")
+		mu.Lock()
+		current = nil
+		mu.Unlock()
+	})
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	const key = "test.layer"
+	SetDefault(key, "default")
+
+	tests := []struct {
+		name    string
+		project string
+		user    string
+		env     string
+		flag    string
+		want    string
+	}{
+		{name: "default", want: "default"},
+		{name: "project over default", project: "test:\n  layer: project\n", want: "project"},
+		{name: "user over project", project: "test:\n  layer: project\n", user: "test:\n  layer: user\n", want: "user"},
+		{name: "env over user", user: "test:\n  layer: user\n", env: "env", want: "env"},
+		{name: "flag over env", user: "test:\n  layer: user\n", env: "env", flag: "flag", want: "flag"},
+		{name: "unset flag is ignored", env: "env", want: "env"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withFiles(t, tt.user, tt.project)
+			if tt.env != "" {
+				t.Setenv(EnvPrefix()+"_TEST_LAYER", tt.env)
+			}
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			flags.String("layer", "flag-default", "")
+			if tt.flag != "" {
+				if err := flags.Set("layer", tt.flag); err != nil {
+					t.Fatal(err)
+				}
+			}
+			BindFlag(key, flags.Lookup("layer"))
+			t.Cleanup(func() {
+				mu.Lock()
+				delete(flagBinds, key)
+				mu.Unlock()
+			})
+
+			c, err := Load()
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if got := c.GetString(key); got != tt.want {
+				t.Errorf("%s = %q, want %q", key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetDoesNotKeepADegradedConfig(t *testing.T) {
+	withFiles(t, "test: [unterminated\n", "")
+	if c := Get(); c.UserFile() == "" {
+		t.Fatal("Get() returned no configuration")
+	}
+	mu.RLock()
+	cached := current
+	mu.RUnlock()
+	if cached != nil {
+		t.Fatal("Get() kept the configuration built after a failed Load")
+	}
+
+	writeFile(t, UserFile(), "test:\n  fixed: true\n")
+	if !Get().GetBool("test.fixed") {
+		t.Error("Get() did not read the fixed user file")
+	}
+}
+
+func TestSetValueKeepsComments(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		key   string
+		value string
+		unset bool
+		want  string
+	}{
+		{
+			name:  "replace a value",
+			file:  "# Logging\nlog:\n  level: info # the default\n  format: text\n",
+			key:   "log.level",
+			value: "debug",
+			want:  "# Logging\nlog:\n  level: debug # the default\n  format: text\n",
+		},
+		{
+			name:  "add a section",
+			file:  "# Top\nlog:\n  level: info\n",
+			key:   "Server.Port",
+			value: "8080",
+			want:  "# Top\nlog:\n  level: info\nserver:\n  port: 8080\n",
+		},
+		{
+			name:  "create the file",
+			key:   "app.name",
+			value: "demo",
+			want:  "app:\n  name: demo\n",
+		},
+		{
+			name:  "unset a key and its empty section",
+			file:  "# Keep me\n\n# Goes with log\nlog:\n  level: info\nserver:\n  port: 8080\n",
+			key:   "log.level",
+			unset: true,
+			want:  "# Keep me\n\nserver:\n  port: 8080\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withFiles(t, tt.file, "")
+			if tt.unset {
+				removed, err := UnsetValue(tt.key)
+				if err != nil || !removed {
+					t.Fatalf("UnsetValue() = %v, %v", removed, err)
+				}
+			} else if err := SetValue(tt.key, tt.value); err != nil {
+				t.Fatalf("SetValue() error = %v", err)
+			}
+			data, err := os.ReadFile(UserFile())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("file =\n%s\nwant\n%s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindFlagSeveralCommands(t *testing.T) {
+	const key = "test.shared"
+	withFiles(t, "", "")
+	first := pflag.NewFlagSet("first", pflag.ContinueOnError)
+	first.Int("shared", 1, "")
+	second := pflag.NewFlagSet("second", pflag.ContinueOnError)
+	second.Int("shared", 1, "")
+	BindFlag(key, first.Lookup("shared"))
+	BindFlag(key, second.Lookup("shared"))
+	t.Cleanup(func() {
+		mu.Lock()
+		delete(flagBinds, key)
+		mu.Unlock()
+	})
+
+	if err := second.Set("shared", "7"); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := c.GetInt(key); got != 7 {
+		t.Errorf("%s = %d, want the value of the flag that was set, 7", key, got)
+	}
+}
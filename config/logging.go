@@ -0,0 +1,167 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	gl "github.com/rafa-mori/goforge/logger"
+	"github.com/rafa-mori/goforge/paths"
+)
+
+// Logging settings live under the "log" section:
+//
+//	log:
+//	  level: info,db=debug    # global level, then per-module overrides
+//	  format: text            # text, json or logfmt
+//	  debug: false
+//	  trace: false
+//	  dir: /var/log/goforge   # write to <dir>/<file> instead of stdout
+//	  file: goforge.log       # relative to dir; an absolute path needs no dir
+//	  max_size_mb: 100        # rotate when the file would grow past this
+//	  max_age: 24h            # rotate files older than this
+//	  max_backups: 7          # rotated files to keep
+//	  compress: true          # gzip rotated files
+//	  async: false            # write from a background goroutine
+//	  buffer_size: 1024       # lines buffered in async mode
+//	  overflow: block         # full buffer: block, drop_newest or drop_oldest
+//	  redact: true            # mask secrets in messages and fields
+//	  redact_keys: [pin]      # key fragments to mask, on top of the defaults
+//	  redact_patterns: []     # regexps to mask, on top of the defaults
+
+// Keys of the log settings.
+const (
+	KeyLogLevel          = "log.level"
+	KeyLogFormat         = "log.format"
+	KeyLogDir            = "log.dir"
+	KeyLogFile           = "log.file"
+	KeyLogMaxSizeMB      = "log.max_size_mb"
+	KeyLogMaxAge         = "log.max_age"
+	KeyLogMaxBackups     = "log.max_backups"
+	KeyLogCompress       = "log.compress"
+	KeyLogAsync          = "log.async"
+	KeyLogBufferSize     = "log.buffer_size"
+	KeyLogOverflow       = "log.overflow"
+	KeyLogRedact         = "log.redact"
+	KeyLogRedactKeys     = "log.redact_keys"
+	KeyLogRedactPatterns = "log.redact_patterns"
+)
+
+func init() {
+	SetDefault(KeyLogMaxSizeMB, 100)
+	SetDefault(KeyLogMaxAge, "24h")
+	SetDefault(KeyLogMaxBackups, 7)
+	SetDefault(KeyLogBufferSize, gl.DefaultBufferSize)
+	SetDefault(KeyLogOverflow, string(gl.OverflowBlock))
+	SetDefault(KeyLogRedact, true)
+	AddValidator(validateLogging)
+}
+
+func validateLogging(c *Config) error {
+	if c.IsSet(KeyLogLevel) {
+		if _, _, err := gl.ParseLevelSpec(c.GetString(KeyLogLevel)); err != nil {
+			return fmt.Errorf("%s: %w", KeyLogLevel, err)
+		}
+	}
+	if _, ok := gl.ParseFormat(c.GetString(KeyLogFormat)); !ok {
+		return fmt.Errorf("%s: unknown format %q", KeyLogFormat, c.GetString(KeyLogFormat))
+	}
+	if _, ok := gl.ParseOverflowPolicy(c.GetString(KeyLogOverflow)); !ok {
+		return fmt.Errorf("%s: unknown policy %q", KeyLogOverflow, c.GetString(KeyLogOverflow))
+	}
+	if err := gl.ValidateRedaction(redactOptions(c)); err != nil {
+		return fmt.Errorf("%s: %w", KeyLogRedactPatterns, err)
+	}
+	if age := c.GetString(KeyLogMaxAge); age != "" {
+		if _, err := time.ParseDuration(age); err != nil {
+			return fmt.Errorf("%s: %w", KeyLogMaxAge, err)
+		}
+	}
+	return nil
+}
+
+func redactOptions(c *Config) gl.RedactOptions {
+	return gl.RedactOptions{
+		Disabled: !c.GetBool(KeyLogRedact),
+		Keys:     c.GetStringSlice(KeyLogRedactKeys),
+		Patterns: c.GetStringSlice(KeyLogRedactPatterns),
+	}
+}
+
+// LogFilePath returns the log file configured in c, or "" for stdout.
+func LogFilePath(c *Config) string {
+	dir, file := c.GetString(KeyLogDir), c.GetString(KeyLogFile)
+	switch {
+	case file != "" && filepath.IsAbs(file):
+		return file
+	case dir == "":
+		return ""
+	case file == "":
+		file = paths.AppName() + ".log"
+	}
+	return filepath.Join(dir, file)
+}
+
+// ApplyLogging applies the level, format and redaction settings of c to the
+// global logger. Settings that are not set in any layer leave the logger
+// untouched. The output settings are applied by ApplyLogOutput.
+func ApplyLogging(c *Config) {
+	// Redaction comes first, so nothing logged below leaks.
+	if err := gl.SetRedaction(redactOptions(c)); err != nil {
+		gl.Log("error", err.Error())
+	}
+	if c.IsSet(KeyLogLevel) {
+		// Validated on load, so the spec parses.
+		_ = gl.SetLevelSpec(c.GetString(KeyLogLevel))
+	}
+	if c.IsSet("log.trace") {
+		gl.Logger.SetShowTrace(c.GetBool("log.trace"))
+	}
+	if c.IsSet("log.debug") {
+		gl.SetDebug(c.GetBool("log.debug"))
+	}
+	if c.IsSet(KeyLogFormat) {
+		if err := gl.SetFormat(c.GetString(KeyLogFormat)); err != nil {
+			gl.Log("error", err.Error())
+		}
+	}
+	if logOutput.Load() {
+		applyLogOutput(c)
+	}
+}
+
+// logOutput is set once ApplyLogOutput ran, so reloads apply the output
+// settings too.
+var logOutput atomic.Bool
+
+// ApplyLogOutput applies the log file, rotation and async settings of c and
+// keeps applying them on reload. Only the long-running service calls it:
+// short-lived commands log to stdout and leave the file to the service.
+func ApplyLogOutput(c *Config) {
+	logOutput.Store(true)
+	applyLogOutput(c)
+}
+
+func applyLogOutput(c *Config) {
+	opts := gl.RotateOptions{
+		MaxSize:    int64(c.GetInt(KeyLogMaxSizeMB)) << 20,
+		MaxAge:     c.GetDuration(KeyLogMaxAge),
+		MaxBackups: c.GetInt(KeyLogMaxBackups),
+		Compress:   c.GetBool(KeyLogCompress),
+	}
+	if err := gl.SetFile(LogFilePath(c), opts); err != nil {
+		gl.Log("error", "Cannot write logs to "+LogFilePath(c)+": "+err.Error())
+	}
+
+	async := gl.AsyncOptions{Overflow: gl.OverflowPolicy(c.GetString(KeyLogOverflow))}
+	if c.GetBool(KeyLogAsync) {
+		async.BufferSize = c.GetInt(KeyLogBufferSize)
+		if async.BufferSize <= 0 {
+			async.BufferSize = gl.DefaultBufferSize
+		}
+	}
+	if err := gl.SetAsync(async); err != nil {
+		gl.Log("error", err.Error())
+	}
+}
@@ -0,0 +1,41 @@
+package config
+
+import "time"
+
+// Section is the namespaced configuration of one module, stored under
+// "modules.<name>". A Section always reads from the current configuration,
+// so it keeps working across reloads.
+type Section struct {
+	name string
+}
+
+// Module returns the configuration section of the named module.
+func Module(name string) *Section {
+	return &Section{name: name}
+}
+
+// Name returns the module name of the section.
+func (s *Section) Name() string { return s.name }
+
+// Key returns the fully qualified key of a key inside the section.
+func (s *Section) Key(key string) string {
+	if key == "" {
+		return ModulesKey + "." + s.name
+	}
+	return ModulesKey + "." + s.name + "." + key
+}
+
+// SetDefault registers the default value of a key inside the section.
+func (s *Section) SetDefault(key string, value any) { SetDefault(s.Key(key), value) }
+
+func (s *Section) Get(key string) any                   { return Get().Get(s.Key(key)) }
+func (s *Section) GetString(key string) string          { return Get().GetString(s.Key(key)) }
+func (s *Section) GetBool(key string) bool              { return Get().GetBool(s.Key(key)) }
+func (s *Section) GetInt(key string) int                { return Get().GetInt(s.Key(key)) }
+func (s *Section) GetFloat64(key string) float64        { return Get().GetFloat64(s.Key(key)) }
+func (s *Section) GetDuration(key string) time.Duration { return Get().GetDuration(s.Key(key)) }
+func (s *Section) GetStringSlice(key string) []string   { return Get().GetStringSlice(s.Key(key)) }
+func (s *Section) IsSet(key string) bool                { return Get().IsSet(s.Key(key)) }
+
+// Unmarshal decodes the whole section into out.
+func (s *Section) Unmarshal(out any) error { return Get().Unmarshal(s.Key(""), out) }
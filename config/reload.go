@@ -0,0 +1,257 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rafa-mori/goforge/events"
+	gl "github.com/rafa-mori/goforge/logger"
+)
+
+// ReloadFunc is called after a reload with the previous and the new
+// configuration. Errors are logged; the new configuration stays in place.
+type ReloadFunc func(prev, next *Config) error
+
+// debounceDelay coalesces the burst of events editors produce on save.
+const debounceDelay = 250 * time.Millisecond
+
+var (
+	reloadMu  sync.Mutex
+	callbacks []ReloadFunc
+	hooks     []func() (end func())
+)
+
+// AroundReload registers fn to be called when a reload begins. The function
+// it returns is called when the reload is over, whether the new
+// configuration was applied or rejected.
+func AroundReload(fn func() (end func())) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	hooks = append(hooks, fn)
+}
+
+// OnReload registers fn to be called after every successful reload.
+func OnReload(fn ReloadFunc) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	callbacks = append(callbacks, fn)
+}
+
+// Reload reads and validates every layer again. An invalid configuration
+// is rejected and logged, keeping the current one. Otherwise it becomes
+// current, the logging settings are applied and the OnReload callbacks
+// run. Callbacks run without any lock held, so they may call Reload or
+// OnReload themselves.
+func Reload() (*Config, error) {
+	reloadMu.Lock()
+	begins := append([]func() func(){}, hooks...)
+	reloadMu.Unlock()
+	for _, begin := range begins {
+		if end := begin(); end != nil {
+			defer end()
+		}
+	}
+
+	prev, c, list, err := swapConfig()
+	if err != nil {
+		gl.Log("error", "Configuration reload rejected, keeping the current configuration: "+err.Error())
+		return prev, err
+	}
+	for _, fn := range list {
+		if err := fn(prev, c); err != nil {
+			gl.Log("error", "Configuration reload callback failed: "+err.Error())
+		}
+	}
+	gl.Log("info", "Configuration reloaded")
+	events.Publish(events.TopicConfigReloaded, c)
+	return c, nil
+}
+
+// swapConfig reads and validates a new configuration and makes it current.
+// It returns the previous configuration, the new one and the callbacks to
+// run.
+func swapConfig() (prev, next *Config, list []ReloadFunc, err error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	prev = Get()
+	next, err = Read()
+	if err == nil {
+		err = next.Validate()
+	}
+	if err != nil {
+		return prev, nil, nil, err
+	}
+
+	mu.Lock()
+	current = next
+	mu.Unlock()
+	ApplyLogging(next)
+	return prev, next, append([]ReloadFunc(nil), callbacks...), nil
+}
+
+// Section returns the settings of a module section as a map.
+func (c *Config) Section(name string) map[string]any {
+	return c.v.GetStringMap(Module(name).Key(""))
+}
+
+// Watcher reloads the configuration on SIGHUP and whenever the user or
+// project file changes. SIGHUP also reopens the log file, for logrotate.
+// It implements goforge.Lifecycle.
+type Watcher struct {
+	// mu guards fsw, which Health reads from the watchdog goroutine.
+	mu     sync.Mutex
+	fsw    *fsnotify.Watcher
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewWatcher returns a Watcher; it does nothing until started.
+func NewWatcher() *Watcher {
+	return &Watcher{}
+}
+
+// Init creates the file watcher and watches the directories of the
+// configuration files, so files created or replaced by editors are seen.
+func (w *Watcher) Init() error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.fsw = fsw
+	w.mu.Unlock()
+	for _, dir := range w.dirs() {
+		if err := fsw.Add(dir); err != nil {
+			gl.Log("debug", "Not watching "+dir+": "+err.Error())
+		}
+	}
+	return nil
+}
+
+// Start watches for SIGHUP and file changes in the background.
+func (w *Watcher) Start(ctx context.Context) error {
+	w.mu.Lock()
+	fsw := w.fsw
+	w.mu.Unlock()
+	if fsw == nil {
+		if err := w.Init(); err != nil {
+			return err
+		}
+		w.mu.Lock()
+		fsw = w.fsw
+		w.mu.Unlock()
+	}
+	ctx, w.cancel = context.WithCancel(ctx)
+	w.done = make(chan struct{})
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer close(w.done)
+		defer signal.Stop(hup)
+
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				if err := gl.ReopenFile(); err != nil {
+					gl.Log("error", "Failed to reopen the log file: "+err.Error())
+				}
+				gl.Log("info", "Received SIGHUP, reloading configuration")
+				_, _ = Reload()
+			case ev, ok := <-fsw.Events:
+				if !ok {
+					return
+				}
+				if w.isConfigFile(ev.Name) {
+					debounce = time.After(debounceDelay)
+				}
+			case err, ok := <-fsw.Errors:
+				if !ok {
+					return
+				}
+				gl.Log("warn", "Configuration watcher error: "+err.Error())
+			case <-debounce:
+				debounce = nil
+				gl.Log("info", "Configuration file changed, reloading")
+				_, _ = Reload()
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops watching.
+func (w *Watcher) Stop(ctx context.Context) error {
+	if w.cancel != nil {
+		w.cancel()
+		select {
+		case <-w.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	w.mu.Lock()
+	fsw := w.fsw
+	w.fsw = nil
+	w.mu.Unlock()
+	if fsw != nil {
+		return fsw.Close()
+	}
+	return nil
+}
+
+// Health reports an error only when the watcher is not running. A failing
+// reload does not make it unhealthy: the previous configuration is kept
+// rather than breaking the service.
+func (w *Watcher) Health() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.fsw == nil {
+		return errors.New("configuration watcher is not running")
+	}
+	return nil
+}
+
+func (w *Watcher) files() []string {
+	c := Get()
+	files := []string{c.UserFile()}
+	if c.ProjectFile() != "" {
+		files = append(files, c.ProjectFile())
+	}
+	return files
+}
+
+func (w *Watcher) dirs() []string {
+	seen := make(map[string]bool)
+	dirs := make([]string, 0, 2)
+	for _, file := range w.files() {
+		dir := filepath.Dir(file)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+func (w *Watcher) isConfigFile(name string) bool {
+	clean := filepath.Clean(name)
+	for _, file := range w.files() {
+		if filepath.Clean(file) == clean {
+			return true
+		}
+	}
+	return false
+}
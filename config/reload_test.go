@@ -0,0 +1,93 @@
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReloadCallbacksMayReenter(t *testing.T) {
+	withFiles(t, "test:\n  value: 1\n", "")
+	saved := callbacks
+	t.Cleanup(func() {
+		reloadMu.Lock()
+		callbacks = saved
+		reloadMu.Unlock()
+	})
+
+	calls := 0
+	OnReload(func(prev, next *Config) error {
+		calls++
+		if calls == 1 {
+			OnReload(func(*Config, *Config) error { return nil })
+			if _, err := Reload(); err != nil {
+				t.Errorf("nested Reload() error = %v", err)
+			}
+		}
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := Reload(); err != nil {
+			t.Errorf("Reload() error = %v", err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reload deadlocked")
+	}
+	if calls != 2 {
+		t.Errorf("callback ran %d times, want 2", calls)
+	}
+}
+
+func TestReloadRejectsInvalidFile(t *testing.T) {
+	withFiles(t, "test:\n  value: 1\n", "")
+	if _, err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, UserFile(), "test: [unterminated\n")
+	prev, err := Reload()
+	if err == nil {
+		t.Fatal("Reload() accepted an invalid file")
+	}
+	if Get() != prev || prev.GetInt("test.value") != 1 {
+		t.Error("Reload() replaced the configuration after a rejected reload")
+	}
+}
+
+func TestWatcherHealthFollowsStartAndStop(t *testing.T) {
+	withFiles(t, "test:\n  value: 1\n", "")
+	w := &Watcher{}
+	if err := w.Health(); err == nil {
+		t.Error("Health() = nil before Start")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = w.Health()
+		}
+	}()
+	if err := w.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if err := w.Health(); err != nil {
+		t.Errorf("Health() after Start = %v", err)
+	}
+
+	if err := w.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Health(); err == nil {
+		t.Error("Health() = nil after Stop")
+	}
+	if err := w.Stop(context.Background()); err != nil {
+		t.Errorf("second Stop() = %v", err)
+	}
+}
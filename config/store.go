@@ -0,0 +1,167 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The user file is edited as a YAML node tree, so that the comments, key
+// order and formatting of the rest of the file survive SetValue and
+// UnsetValue. Keys are matched case-insensitively, as viper reads them.
+
+// SetValue writes key to the user configuration file. The value is stored
+// as a boolean, integer or float when it parses as one, as a string
+// otherwise.
+func SetValue(key, value string) error {
+	if key == "" {
+		return errors.New("empty key")
+	}
+	doc, err := readUserDocument()
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := node.Encode(parseValue(value)); err != nil {
+		return err
+	}
+	setNode(doc.Content[0], strings.Split(strings.ToLower(key), "."), &node)
+	return writeUserDocument(doc)
+}
+
+// UnsetValue removes key from the user configuration file. It reports
+// whether the key was present.
+func UnsetValue(key string) (bool, error) {
+	doc, err := readUserDocument()
+	if err != nil {
+		return false, err
+	}
+	if !deleteNode(doc.Content[0], strings.Split(strings.ToLower(key), ".")) {
+		return false, nil
+	}
+	return true, writeUserDocument(doc)
+}
+
+// readUserDocument parses the user file, returning a document holding an
+// empty mapping when the file does not exist or is empty.
+func readUserDocument() (*yaml.Node, error) {
+	file := UserFile()
+	data, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", file, err)
+	}
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file %s: top level is not a mapping", file)
+	}
+	return &doc, nil
+}
+
+func writeUserDocument(doc *yaml.Node) error {
+	file := UserFile()
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", file, err)
+	}
+	return nil
+}
+
+func parseValue(value string) any {
+	if b, err := strconv.ParseBool(value); err == nil {
+		return b
+	}
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	return value
+}
+
+// lookupNode returns the index of the value of key in the mapping m, or -1.
+func lookupNode(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if strings.EqualFold(m.Content[i].Value, key) {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// setNode stores value under path in the mapping m, creating or replacing
+// the intermediate mappings. A replaced node keeps its comments.
+func setNode(m *yaml.Node, path []string, value *yaml.Node) {
+	for _, key := range path[:len(path)-1] {
+		i := lookupNode(m, key)
+		if i < 0 {
+			next := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, next)
+			m = next
+			continue
+		}
+		if m.Content[i].Kind != yaml.MappingNode {
+			replaceNode(m.Content[i], &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+		}
+		m = m.Content[i]
+	}
+	key := path[len(path)-1]
+	if i := lookupNode(m, key); i >= 0 {
+		replaceNode(m.Content[i], value)
+		return
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// replaceNode overwrites old with value, keeping the comments of old.
+func replaceNode(old, value *yaml.Node) {
+	head, line, foot := old.HeadComment, old.LineComment, old.FootComment
+	*old = *value
+	old.HeadComment, old.LineComment, old.FootComment = head, line, foot
+}
+
+// deleteNode removes path from the mapping m, along with the mappings left
+// empty. It reports whether path was present.
+func deleteNode(m *yaml.Node, path []string) bool {
+	i := lookupNode(m, path[0])
+	if i < 0 {
+		return false
+	}
+	if len(path) > 1 {
+		next := m.Content[i]
+		if next.Kind != yaml.MappingNode || !deleteNode(next, path[1:]) {
+			return false
+		}
+		if len(next.Content) > 0 {
+			return true
+		}
+	}
+	m.Content = append(m.Content[:i-1], m.Content[i+1:]...)
+	return true
+}
@@ -0,0 +1,340 @@
+// Package control implements the local control plane of the running
+// service: a JSON-RPC server on a unix socket next to the pidfile, and the
+// client used by the ctl command. Access is restricted to the owner of
+// the process through the permissions of the socket and its directory.
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rafa-mori/goforge/daemon"
+	"github.com/rafa-mori/goforge/health"
+	"github.com/rafa-mori/goforge/lifecycle"
+	gl "github.com/rafa-mori/goforge/logger"
+	"github.com/rafa-mori/goforge/paths"
+	"github.com/rafa-mori/goforge/registry"
+	"github.com/rafa-mori/goforge/scheduler"
+	"github.com/rafa-mori/goforge/tasks"
+)
+
+// callTimeout bounds a whole client call.
+const callTimeout = 30 * time.Second
+
+// ServiceName is the name the RPC methods are registered under, e.g.
+// "Control.Modules".
+const ServiceName = "Control"
+
+// SocketPath returns the location of the control socket.
+func SocketPath() string {
+	return filepath.Join(paths.RuntimeDir(), paths.AppName()+".sock")
+}
+
+// Empty is the argument of methods that take none.
+type Empty struct{}
+
+// ModuleStatus describes one module of the running service.
+type ModuleStatus struct {
+	Name    string `json:"name"`
+	Active  bool   `json:"active"`
+	Managed bool   `json:"managed"`
+	Health  string `json:"health,omitempty"`
+}
+
+// Service holds the RPC methods. Every exported method follows the
+// net/rpc convention: func (s *Service) Name(args T, reply *R) error.
+type Service struct {
+	mgr    *lifecycle.Manager
+	reload func() error
+}
+
+// Modules lists the registered modules and the units run by the manager.
+func (s *Service) Modules(_ Empty, reply *[]ModuleStatus) error {
+	health := map[string]error{}
+	if s.mgr != nil {
+		health = s.mgr.Health()
+	}
+	seen := make(map[string]bool)
+	list := make([]ModuleStatus, 0)
+	for _, mod := range registry.Modules() {
+		st := ModuleStatus{Name: mod.Module(), Active: mod.Active()}
+		if err, managed := health[st.Name]; managed {
+			st.Managed = true
+			st.Health = healthString(err)
+		}
+		seen[st.Name] = true
+		list = append(list, st)
+	}
+	for name, err := range health {
+		if !seen[name] {
+			list = append(list, ModuleStatus{Name: name, Active: true, Managed: true, Health: healthString(err)})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	*reply = list
+	return nil
+}
+
+// SetLogLevel changes the log levels from a spec such as
+// "info,db=debug". The reply is the resulting spec.
+func (s *Service) SetLogLevel(spec string, reply *string) error {
+	if err := gl.SetLevelSpec(spec); err != nil {
+		return err
+	}
+	*reply = gl.LevelSpec()
+	gl.Log("info", "Log level changed to "+*reply+" through the control socket")
+	return nil
+}
+
+// SetDebug toggles debug mode.
+func (s *Service) SetDebug(enabled bool, reply *bool) error {
+	gl.SetDebug(enabled)
+	gl.Logf("info", "Debug mode set to %t through the control socket", enabled)
+	*reply = enabled
+	return nil
+}
+
+// Reload triggers a configuration reload.
+func (s *Service) Reload(_ Empty, reply *string) error {
+	if s.reload == nil {
+		return errors.New("reload is not supported")
+	}
+	if err := s.reload(); err != nil {
+		return err
+	}
+	*reply = "configuration reloaded"
+	return nil
+}
+
+// Health runs the checks of kind ("live", "ready" or "all") and adds the
+// health of the lifecycle modules to readiness reports.
+func (s *Service) Health(kind string, reply *health.Report) error {
+	k, err := health.ParseKind(kind)
+	if err != nil {
+		return err
+	}
+	report := health.Run(context.Background(), k)
+	if k&health.Readiness != 0 && s.mgr != nil {
+		report.AddErrors(s.mgr.Health())
+	}
+	*reply = *report
+	return nil
+}
+
+// Jobs lists the jobs of the scheduler.
+func (s *Service) Jobs(_ Empty, reply *[]scheduler.Status) error {
+	*reply = scheduler.Default.Jobs()
+	return nil
+}
+
+// RunJob starts the named job now and returns without waiting for it, so
+// that long jobs outlive neither the call timeout nor the service. Its
+// outcome is reported by Jobs.
+func (s *Service) RunJob(name string, reply *string) error {
+	if err := scheduler.Default.Trigger(name); err != nil {
+		return err
+	}
+	*reply = "job " + name + " started, see the jobs list for its result"
+	return nil
+}
+
+// PurgeArgs selects the tasks removed by PurgeTasks: the listed ids, or
+// every task in one of States when IDs is empty.
+type PurgeArgs struct {
+	IDs    []string      `json:"ids,omitempty"`
+	States []tasks.State `json:"states,omitempty"`
+}
+
+// RetryTasks makes the given dead tasks, or every dead task, pending again.
+func (s *Service) RetryTasks(ids []string, reply *[]string) error {
+	retried, err := tasks.Default.Retry(ids...)
+	*reply = retried
+	return err
+}
+
+// PurgeTasks removes tasks from the queue.
+func (s *Service) PurgeTasks(args PurgeArgs, reply *[]string) error {
+	purged, err := tasks.Default.Purge(args.IDs, args.States...)
+	*reply = purged
+	return err
+}
+
+// Stacks returns the stack traces of every goroutine.
+func (s *Service) Stacks(_ Empty, reply *string) error {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			*reply = string(buf[:n])
+			return nil
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+func healthString(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
+
+// Server serves the control Service on a socket that exists from Start to
+// Stop.
+type Server struct {
+	service *Service
+	rpc     *rpc.Server
+
+	// mu guards the fields below, which Health reads from the watchdog
+	// goroutine while Start and Stop write them.
+	mu       sync.Mutex
+	listener net.Listener
+	// removeHook unregisters the socket cleanup run on a forced exit.
+	removeHook func()
+	conns      map[net.Conn]struct{}
+}
+
+// NewServer returns a Server exposing mgr's units. reload is called by the
+// Reload method.
+func NewServer(mgr *lifecycle.Manager, reload func() error) *Server {
+	return &Server{service: &Service{mgr: mgr, reload: reload}}
+}
+
+// Init registers the RPC service.
+func (s *Server) Init() error {
+	s.rpc = rpc.NewServer()
+	return s.rpc.RegisterName(ServiceName, s.service)
+}
+
+// Start listens on the control socket, replacing a stale socket file.
+func (s *Server) Start(_ context.Context) error {
+	if s.rpc == nil {
+		if err := s.Init(); err != nil {
+			return err
+		}
+	}
+	// The socket is only reachable through its directory, which
+	// EnsureRuntimeDir guarantees to be private to the current user, so
+	// it is never exposed before the chmod below.
+	path := SocketPath()
+	if _, err := paths.EnsureRuntimeDir(); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		_ = ln.Close()
+		return err
+	}
+	s.mu.Lock()
+	s.listener = ln
+	s.conns = make(map[net.Conn]struct{})
+	// Closing the listener removes the socket file, which a forced exit
+	// skips.
+	s.removeHook = daemon.AtExit(func() { _ = os.Remove(path) })
+	s.mu.Unlock()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					gl.Log("error", "Control socket failed: "+err.Error())
+				}
+				return
+			}
+			if !s.track(conn) {
+				_ = conn.Close()
+				return
+			}
+			go func() {
+				defer s.untrack(conn)
+				s.rpc.ServeCodec(jsonrpc.NewServerCodec(conn))
+			}()
+		}
+	}()
+
+	gl.Log("debug", "Control socket listening on "+path)
+	return nil
+}
+
+// Stop closes the socket and the open connections, and removes the socket
+// file.
+func (s *Server) Stop(_ context.Context) error {
+	s.mu.Lock()
+	ln, removeHook, conns := s.listener, s.removeHook, s.conns
+	s.listener, s.removeHook, s.conns = nil, nil, nil
+	s.mu.Unlock()
+	if ln == nil {
+		return nil
+	}
+
+	err := ln.Close()
+	_ = os.Remove(SocketPath())
+	if removeHook != nil {
+		removeHook()
+	}
+	for conn := range conns {
+		_ = conn.Close()
+	}
+	return err
+}
+
+// track records an accepted connection. It reports false once the server
+// is stopping.
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conns == nil {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+}
+
+// Health reports nil while the socket is open.
+func (s *Server) Health() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return errors.New("control socket is not listening")
+	}
+	return nil
+}
+
+// Call invokes method (without the service prefix) on the running
+// service and stores the result in reply.
+func Call(method string, args, reply any) error {
+	if err := paths.CheckRuntimeDir(); err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("unix", SocketPath(), 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("cannot reach the running service at %s: %w", SocketPath(), err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(callTimeout))
+	client := jsonrpc.NewClient(conn)
+	defer func() { _ = client.Close() }()
+	return client.Call(ServiceName+"."+method, args, reply)
+}
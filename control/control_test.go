@@ -0,0 +1,54 @@
+package control
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestServerHealthFollowsStartAndStop(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	s := NewServer(nil, nil)
+	if err := s.Health(); err == nil {
+		t.Error("Health() = nil before Start")
+	}
+
+	// The watchdog reads Health while the modules start.
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = s.Health()
+			}
+		}
+	}()
+	err := s.Start(context.Background())
+	close(stop)
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Health(); err != nil {
+		t.Errorf("Health() after Start = %v", err)
+	}
+
+	var stacks string
+	if err := Call("Stacks", Empty{}, &stacks); err != nil || stacks == "" {
+		t.Errorf("Call(Stacks) = %q, %v", stacks, err)
+	}
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Health(); err == nil {
+		t.Error("Health() = nil after Stop")
+	}
+	if err := s.Stop(context.Background()); err != nil {
+		t.Errorf("second Stop() = %v", err)
+	}
+}
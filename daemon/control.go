@@ -0,0 +1,130 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	gl "github.com/rafa-mori/goforge/logger"
+	"github.com/rafa-mori/goforge/paths"
+)
+
+// pollInterval is how often the process state is checked while waiting.
+const pollInterval = 100 * time.Millisecond
+
+// Stop sends SIGTERM to the running instance and waits up to timeout for
+// it to exit. When force is set, an instance still alive after timeout is
+// killed.
+func Stop(timeout time.Duration, force bool) (*Info, error) {
+	info, err := Running()
+	if err != nil {
+		return nil, err
+	}
+	gl.Logf("info", "Stopping pid %d", info.PID)
+	if err := syscall.Kill(info.PID, syscall.SIGTERM); err != nil {
+		return info, fmt.Errorf("signal pid %d: %w", info.PID, err)
+	}
+	if waitExit(info.PID, timeout) {
+		_ = removeIfOwnedBy(info.PID)
+		return info, nil
+	}
+	if !force {
+		return info, fmt.Errorf("pid %d did not exit within %s", info.PID, timeout)
+	}
+	gl.Logf("warn", "Pid %d did not exit within %s, killing it", info.PID, timeout)
+	if err := syscall.Kill(info.PID, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return info, fmt.Errorf("kill pid %d: %w", info.PID, err)
+	}
+	waitExit(info.PID, timeout)
+	_ = removeIfOwnedBy(info.PID)
+	return info, nil
+}
+
+// Restart stops the running instance, if any, and starts a new one in the
+// background with the arguments the previous instance was started with.
+// When nothing is running, defaultArgs are used instead.
+func Restart(timeout time.Duration, defaultArgs []string) (*Info, error) {
+	args := defaultArgs
+	exe := ""
+	if info, err := Stop(timeout, false); err == nil {
+		args, exe = info.Args, info.Executable
+	} else if !errors.Is(err, ErrNotRunning) {
+		return nil, err
+	}
+	return StartDetached(exe, args, timeout)
+}
+
+// StartDetached starts exe (the current executable when "") with args in
+// a new session, with its output appended to OutputPath, and waits up to
+// timeout for it to write the pidfile.
+func StartDetached(exe string, args []string, timeout time.Duration) (*Info, error) {
+	if exe == "" {
+		var err error
+		if exe, err = os.Executable(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := paths.EnsureRuntimeDir(); err != nil {
+		return nil, err
+	}
+	out, err := os.OpenFile(OutputPath(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = out.Close() }()
+
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	pid := cmd.Process.Pid
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+	for {
+		select {
+		case err := <-exited:
+			return nil, fmt.Errorf("pid %d exited during startup (%v), see %s", pid, err, OutputPath())
+		case <-deadline:
+			return nil, fmt.Errorf("pid %d did not write its pidfile within %s", pid, timeout)
+		case <-ticker.C:
+			if info, err := ReadPidfile(); err == nil && info.PID == pid {
+				return info, nil
+			}
+		}
+	}
+}
+
+// OutputPath returns the file receiving the output of detached instances.
+func OutputPath() string {
+	return filepath.Join(paths.RuntimeDir(), paths.AppName()+".out")
+}
+
+func waitExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if !Alive(pid) {
+			return true
+		}
+		time.Sleep(pollInterval)
+	}
+	return !Alive(pid)
+}
+
+func removeIfOwnedBy(pid int) error {
+	info, err := ReadPidfile()
+	if err != nil || info.PID != pid {
+		return nil
+	}
+	return os.Remove(PidfilePath())
+}
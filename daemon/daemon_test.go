@@ -0,0 +1,181 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rafa-mori/goforge/paths"
+)
+
+// withRuntimeDir points the runtime directory, and so the pidfile, to a
+// temporary directory.
+func withRuntimeDir(t *testing.T) {
+	t.Helper()
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	if _, err := paths.EnsureRuntimeDir(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeInfo(t *testing.T, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(PidfilePath(), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writePid(t *testing.T, pid int) {
+	t.Helper()
+	data, err := json.Marshal(Info{PID: pid, StartedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeInfo(t, data)
+}
+
+// spawn starts a shell script and reaps it once it exits, so that a killed
+// child does not linger as a zombie that still looks alive.
+func spawn(t *testing.T, script string) int {
+	t.Helper()
+	cmd := exec.Command("sh", "-c", script)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(done)
+	}()
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		<-done
+	})
+	return cmd.Process.Pid
+}
+
+func deadPid(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestPidfile(t *testing.T) {
+	withRuntimeDir(t)
+	info, err := WritePidfile("1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.PID != os.Getpid() || info.Version != "1.2.3" || !slices.Equal(info.Args, os.Args[1:]) {
+		t.Errorf("WritePidfile() = %+v", info)
+	}
+	read, err := ReadPidfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.PID != info.PID || !read.StartedAt.Equal(info.StartedAt) || read.Executable != info.Executable {
+		t.Errorf("ReadPidfile() = %+v, want %+v", read, info)
+	}
+	if _, err := WritePidfile("1.2.3"); !errors.Is(err, ErrRunning) {
+		t.Errorf("second WritePidfile() = %v, want ErrRunning", err)
+	}
+	if running, err := Running(); err != nil || running.PID != info.PID {
+		t.Errorf("Running() = %v, %v", running, err)
+	}
+	if err := RemovePidfile(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadPidfile(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadPidfile() after RemovePidfile = %v, want ErrNotExist", err)
+	}
+	if err := RemovePidfile(); err != nil {
+		t.Errorf("RemovePidfile() without a pidfile = %v", err)
+	}
+}
+
+func TestStalePidfile(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(t *testing.T)
+	}{
+		{"dead process", func(t *testing.T) { writePid(t, deadPid(t)) }},
+		{"invalid pid", func(t *testing.T) { writePid(t, 0) }},
+		{"unreadable", func(t *testing.T) { writeInfo(t, []byte("{not json")) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withRuntimeDir(t)
+			tt.write(t)
+			if _, err := Running(); !errors.Is(err, ErrNotRunning) {
+				t.Errorf("Running() = %v, want ErrNotRunning", err)
+			}
+			if _, err := os.Stat(PidfilePath()); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("stale pidfile kept: %v", err)
+			}
+
+			tt.write(t)
+			if _, err := WritePidfile("1.2.3"); err != nil {
+				t.Errorf("WritePidfile() over a stale pidfile = %v", err)
+			}
+		})
+	}
+}
+
+func TestRemovePidfileKeepsOtherProcess(t *testing.T) {
+	withRuntimeDir(t)
+	writePid(t, spawn(t, "sleep 30"))
+	if err := RemovePidfile(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadPidfile(); err != nil {
+		t.Errorf("pidfile of another process removed: %v", err)
+	}
+}
+
+func TestStop(t *testing.T) {
+	tests := []struct {
+		name string
+		// script ignores SIGTERM when it must be killed.
+		script  string
+		force   bool
+		wantErr bool
+	}{
+		{"exits on SIGTERM", "sleep 30", false, false},
+		{"ignores SIGTERM", `trap "" TERM; while :; do sleep 0.1; done`, false, true},
+		{"killed", `trap "" TERM; while :; do sleep 0.1; done`, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withRuntimeDir(t)
+			pid := spawn(t, tt.script)
+			writePid(t, pid)
+			// Give the shell time to set up the trap.
+			time.Sleep(100 * time.Millisecond)
+
+			info, err := Stop(300*time.Millisecond, tt.force)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Stop() = %v, want error %v", err, tt.wantErr)
+			}
+			if info == nil || info.PID != pid {
+				t.Fatalf("Stop() info = %+v, want pid %d", info, pid)
+			}
+			alive := Alive(pid)
+			_, statErr := os.Stat(PidfilePath())
+			if alive != tt.wantErr || (statErr == nil) != tt.wantErr {
+				t.Errorf("after Stop: alive = %v, pidfile kept = %v, want %v", alive, statErr == nil, tt.wantErr)
+			}
+		})
+	}
+
+	withRuntimeDir(t)
+	if _, err := Stop(time.Second, false); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Stop() without an instance = %v, want ErrNotRunning", err)
+	}
+}
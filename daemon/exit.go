@@ -0,0 +1,49 @@
+package daemon
+
+import (
+	"sort"
+	"sync"
+)
+
+// A forced exit, such as the one following a second termination signal,
+// skips deferred calls. Code owning resources that must not outlive the
+// process registers their cleanup with AtExit for that case.
+
+var (
+	exitMu    sync.Mutex
+	exitHooks = make(map[int]func())
+	exitSeq   int
+)
+
+// AtExit registers fn to run by RunExitHooks. It returns a function
+// unregistering fn, to call once the resource is released normally.
+func AtExit(fn func()) (remove func()) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	exitSeq++
+	id := exitSeq
+	exitHooks[id] = fn
+	return func() {
+		exitMu.Lock()
+		defer exitMu.Unlock()
+		delete(exitHooks, id)
+	}
+}
+
+// RunExitHooks runs and unregisters every hook, most recent first. It is
+// meant to be called right before os.Exit.
+func RunExitHooks() {
+	exitMu.Lock()
+	ids := make([]int, 0, len(exitHooks))
+	for id := range exitHooks {
+		ids = append(ids, id)
+	}
+	hooks := exitHooks
+	exitHooks = make(map[int]func())
+	exitMu.Unlock()
+
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	for _, id := range ids {
+		hooks[id]()
+	}
+}
@@ -0,0 +1,161 @@
+// Package daemon manages the background instance started by the start
+// command: its pidfile and the stop, status and restart operations.
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	gl "github.com/rafa-mori/goforge/logger"
+	"github.com/rafa-mori/goforge/paths"
+)
+
+var (
+	// ErrRunning is returned when another instance owns the pidfile.
+	ErrRunning = errors.New("already running")
+	// ErrNotRunning is returned when no live instance owns the pidfile.
+	ErrNotRunning = errors.New("not running")
+)
+
+// SupervisedEnv returns the environment variable marking an instance run
+// by the supervisor of start --supervise. The supervisor owns the pidfile
+// on its behalf, so that stop and restart act on the supervisor.
+func SupervisedEnv() string {
+	prefix := strings.NewReplacer("-", "_", ".", "_").Replace(paths.AppName())
+	return strings.ToUpper(prefix) + "_SUPERVISED"
+}
+
+// Supervised reports whether the current process runs under the
+// supervisor of start --supervise.
+func Supervised() bool {
+	return os.Getenv(SupervisedEnv()) == "1"
+}
+
+// Info is the content of the pidfile.
+type Info struct {
+	PID        int       `json:"pid"`
+	StartedAt  time.Time `json:"started_at"`
+	Version    string    `json:"version"`
+	Executable string    `json:"executable"`
+	Args       []string  `json:"args"`
+}
+
+// Uptime returns how long the instance has been running.
+func (i *Info) Uptime() time.Duration {
+	return time.Since(i.StartedAt).Truncate(time.Second)
+}
+
+// PidfilePath returns the location of the pidfile.
+func PidfilePath() string {
+	return filepath.Join(paths.RuntimeDir(), paths.AppName()+".pid")
+}
+
+// WritePidfile records the current process in the pidfile. A pidfile left
+// behind by a process that is gone is replaced; one owned by a live
+// process makes it fail with ErrRunning.
+func WritePidfile(version string) (*Info, error) {
+	path := PidfilePath()
+	if _, err := paths.EnsureRuntimeDir(); err != nil {
+		return nil, err
+	}
+
+	exe, _ := os.Executable()
+	info := &Info{
+		PID:        os.Getpid(),
+		StartedAt:  time.Now(),
+		Version:    version,
+		Executable: exe,
+		Args:       os.Args[1:],
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			_, werr := f.Write(data)
+			cerr := f.Close()
+			if werr = errors.Join(werr, cerr); werr != nil {
+				_ = os.Remove(path)
+				return nil, werr
+			}
+			return info, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if running, rerr := Running(); rerr == nil {
+			return nil, fmt.Errorf("pid %d: %w", running.PID, ErrRunning)
+		}
+	}
+	return nil, fmt.Errorf("could not create pidfile %s", path)
+}
+
+// RemovePidfile removes the pidfile if it belongs to the current process.
+func RemovePidfile() error {
+	info, err := ReadPidfile()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if info.PID != os.Getpid() {
+		return nil
+	}
+	return os.Remove(PidfilePath())
+}
+
+// ReadPidfile reads the pidfile without checking the process.
+func ReadPidfile() (*Info, error) {
+	if err := paths.CheckRuntimeDir(); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(PidfilePath())
+	if err != nil {
+		return nil, err
+	}
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("invalid pidfile %s: %w", PidfilePath(), err)
+	}
+	return &info, nil
+}
+
+// Running returns the instance recorded in the pidfile. A stale pidfile,
+// left by a process that no longer exists or unreadable, is removed and
+// ErrNotRunning is returned.
+func Running() (*Info, error) {
+	info, err := ReadPidfile()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotRunning
+		}
+		gl.Log("warn", "Removing unreadable pidfile: "+err.Error())
+		_ = os.Remove(PidfilePath())
+		return nil, ErrNotRunning
+	}
+	if !Alive(info.PID) {
+		gl.Logf("warn", "Removing stale pidfile of pid %d", info.PID)
+		_ = os.Remove(PidfilePath())
+		return nil, ErrNotRunning
+	}
+	return info, nil
+}
+
+// Alive reports whether a process with the given pid exists.
+func Alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
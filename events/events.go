@@ -0,0 +1,308 @@
+// Package events is an in-process publish/subscribe bus letting modules
+// communicate without importing each other. Topics are dot-separated
+// names such as "module.started"; subscription patterns may use "*" for
+// exactly one segment and "**" for any number of segments.
+//
+// Handlers subscribed with Subscribe run synchronously in the publisher's
+// goroutine; handlers subscribed with SubscribeAsync get their own
+// goroutine and buffer, and events that do not fit in the buffer are
+// dropped. A panicking handler is logged and never takes the process down.
+package events
+
+import (
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	gl "github.com/rafa-mori/goforge/logger"
+	"github.com/rafa-mori/goforge/metrics"
+)
+
+// Topics published by the runtime.
+const (
+	// TopicModuleStarted is published with a ModuleEvent once a module
+	// started by the start command is up.
+	TopicModuleStarted = "module.started"
+	// TopicModuleStopped is published with a ModuleEvent once a module
+	// has been stopped.
+	TopicModuleStopped = "module.stopped"
+	// TopicConfigReloaded is published with the new *config.Config after
+	// a successful configuration reload.
+	TopicConfigReloaded = "config.reloaded"
+	// TopicVersionAvailable is published with a VersionEvent when a
+	// version check finds a newer release.
+	TopicVersionAvailable = "version.available"
+)
+
+// DefaultBuffer is the buffer size of async subscribers created with a
+// non-positive size.
+const DefaultBuffer = 64
+
+// dropWarningInterval is the minimum time between two warnings about the
+// events dropped by one subscriber.
+const dropWarningInterval = time.Minute
+
+var (
+	eventsPublished = metrics.NewCounter(metrics.Name("events_published_total"), "Events published.")
+	eventsDropped   = metrics.NewCounter(metrics.Name("events_dropped_total"), "Events dropped because an async subscriber buffer was full, by pattern.", "pattern")
+	handlerPanics   = metrics.NewCounter(metrics.Name("events_handler_panics_total"), "Event handlers that panicked, by pattern.", "pattern")
+)
+
+// Event is a message published on a topic.
+type Event struct {
+	Topic string
+	Data  any
+	Time  time.Time
+}
+
+// ModuleEvent is the data of the module lifecycle events.
+type ModuleEvent struct {
+	Name  string
+	Error string
+}
+
+// VersionEvent is the data of TopicVersionAvailable.
+type VersionEvent struct {
+	Current string
+	Latest  string
+}
+
+// Handler receives the events of a subscription.
+type Handler func(e Event)
+
+// Subscription is a registered handler. Unsubscribe stops the delivery.
+type Subscription struct {
+	bus     *Bus
+	pattern []string
+	raw     string
+	handler Handler
+
+	queue chan Event
+	done  chan struct{}
+	once  sync.Once
+
+	// mu guards the send on queue against its close, and the drop
+	// warnings.
+	mu       sync.Mutex
+	closed   bool
+	dropped  int
+	lastWarn time.Time
+}
+
+// Pattern returns the topic pattern of the subscription.
+func (s *Subscription) Pattern() string { return s.raw }
+
+// Unsubscribe removes the subscription. Async subscriptions finish
+// delivering the events already buffered.
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.bus.remove(s)
+		if s.queue != nil {
+			s.mu.Lock()
+			s.closed = true
+			close(s.queue)
+			s.mu.Unlock()
+		}
+	})
+}
+
+// Wait blocks until an unsubscribed async subscription has delivered its
+// buffered events. It returns immediately for sync subscriptions.
+func (s *Subscription) Wait() {
+	if s.done != nil {
+		<-s.done
+	}
+}
+
+// Bus dispatches published events to the matching subscriptions.
+type Bus struct {
+	mu   sync.RWMutex
+	subs []*Subscription
+}
+
+// Default is the bus used by the package-level functions.
+var Default = New()
+
+// New returns an empty Bus.
+func New() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a synchronous handler on the Default bus.
+func Subscribe(pattern string, h Handler) *Subscription { return Default.Subscribe(pattern, h) }
+
+// SubscribeAsync registers an asynchronous handler on the Default bus.
+func SubscribeAsync(pattern string, buffer int, h Handler) *Subscription {
+	return Default.SubscribeAsync(pattern, buffer, h)
+}
+
+// Publish publishes data on topic on the Default bus.
+func Publish(topic string, data any) { Default.Publish(topic, data) }
+
+// On registers a synchronous handler on the Default bus that receives the
+// data of the events as T. Events whose data is not a T are ignored.
+func On[T any](pattern string, fn func(topic string, data T)) *Subscription {
+	return Default.Subscribe(pattern, typed(fn))
+}
+
+// OnAsync is the asynchronous form of On.
+func OnAsync[T any](pattern string, buffer int, fn func(topic string, data T)) *Subscription {
+	return Default.SubscribeAsync(pattern, buffer, typed(fn))
+}
+
+func typed[T any](fn func(topic string, data T)) Handler {
+	return func(e Event) {
+		if data, ok := e.Data.(T); ok {
+			fn(e.Topic, data)
+		}
+	}
+}
+
+// Subscribe registers h to run in the publisher's goroutine for every
+// event whose topic matches pattern.
+func (b *Bus) Subscribe(pattern string, h Handler) *Subscription {
+	s := &Subscription{bus: b, pattern: split(pattern), raw: pattern, handler: h}
+	b.add(s)
+	return s
+}
+
+// SubscribeAsync registers h to run in a dedicated goroutine for every
+// event whose topic matches pattern. Up to buffer events wait for h;
+// further events are dropped and counted until h catches up.
+func (b *Bus) SubscribeAsync(pattern string, buffer int, h Handler) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	s := &Subscription{
+		bus:     b,
+		pattern: split(pattern),
+		raw:     pattern,
+		handler: h,
+		queue:   make(chan Event, buffer),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		for e := range s.queue {
+			s.deliver(e)
+		}
+	}()
+	b.add(s)
+	return s
+}
+
+// Publish delivers data on topic to every matching subscription. It
+// returns once the sync handlers ran and the event is queued for the async
+// ones.
+func (b *Bus) Publish(topic string, data any) {
+	e := Event{Topic: topic, Data: data, Time: time.Now()}
+	parts := split(topic)
+	eventsPublished.Inc()
+
+	b.mu.RLock()
+	subs := make([]*Subscription, 0, len(b.subs))
+	for _, s := range b.subs {
+		if match(s.pattern, parts) {
+			subs = append(subs, s)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, s := range subs {
+		if s.queue == nil {
+			s.deliver(e)
+			continue
+		}
+		s.enqueue(e)
+	}
+}
+
+// Close unsubscribes every subscription and waits for the async ones to
+// drain.
+func (b *Bus) Close() {
+	b.mu.RLock()
+	subs := append([]*Subscription(nil), b.subs...)
+	b.mu.RUnlock()
+	for _, s := range subs {
+		s.Unsubscribe()
+	}
+	for _, s := range subs {
+		s.Wait()
+	}
+}
+
+func (b *Bus) add(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, s)
+}
+
+func (b *Bus) remove(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, sub := range b.subs {
+		if sub == s {
+			b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// enqueue hands e to an async subscription without blocking. Events
+// reaching a subscription unsubscribed concurrently are ignored. Drops are
+// counted in the metrics and logged at most once per dropWarningInterval.
+func (s *Subscription) enqueue(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.queue <- e:
+		return
+	default:
+	}
+	eventsDropped.Inc(s.raw)
+	s.dropped++
+	if now := time.Now(); now.Sub(s.lastWarn) >= dropWarningInterval {
+		gl.Logf("warn", "%d event(s) dropped, last on %s: buffer of subscriber %q is full", s.dropped, e.Topic, s.raw)
+		s.dropped, s.lastWarn = 0, now
+	}
+}
+
+// deliver runs the handler, isolating the caller from its panics.
+func (s *Subscription) deliver(e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			handlerPanics.Inc(s.raw)
+			gl.Logf("error", "Event handler for %q panicked on %s: %v", s.raw, e.Topic, r)
+			gl.Log("debug", string(debug.Stack()))
+		}
+	}()
+	s.handler(e)
+}
+
+func split(topic string) []string {
+	return strings.Split(topic, ".")
+}
+
+// match reports whether topic matches pattern, where "*" matches one
+// segment and "**" any number of segments, including none.
+func match(pattern, topic []string) bool {
+	for i, p := range pattern {
+		if p == "**" {
+			rest := pattern[i+1:]
+			for j := i; j <= len(topic); j++ {
+				if match(rest, topic[j:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if i >= len(topic) || (p != "*" && p != topic[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(topic)
+}
@@ -0,0 +1,118 @@
+package events
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, topic string
+		want           bool
+	}{
+		{"module.started", "module.started", true},
+		{"module.started", "module.stopped", false},
+		{"module.*", "module.started", true},
+		{"module.*", "module", false},
+		{"module.*", "module.a.b", false},
+		{"**", "module.started", true},
+		{"module.**", "module", true},
+		{"module.**", "module.a.b", true},
+		{"**.stopped", "module.stopped", true},
+		{"**.stopped", "module.started", false},
+		{"*.*", "config.reloaded", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+"~"+tt.topic, func(t *testing.T) {
+			if got := match(split(tt.pattern), split(tt.topic)); got != tt.want {
+				t.Errorf("match(%q, %q) = %v, want %v", tt.pattern, tt.topic, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublishDuringUnsubscribe(t *testing.T) {
+	b := New()
+	var got atomic.Int64
+	subs := make([]*Subscription, 50)
+	for i := range subs {
+		subs[i] = b.SubscribeAsync("a.*", 1, func(Event) { got.Add(1) })
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			b.Publish("a.b", i)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for _, s := range subs {
+			s.Unsubscribe()
+		}
+	}()
+	wg.Wait()
+	b.Close()
+	for _, s := range subs {
+		s.Wait()
+	}
+	if got.Load() > 50*1000 {
+		t.Errorf("delivered %d events, more than published", got.Load())
+	}
+}
+
+func TestHandlerPanicIsContained(t *testing.T) {
+	b := New()
+	var after atomic.Bool
+	b.Subscribe("x", func(Event) { panic("boom") })
+	b.Subscribe("x", func(Event) { after.Store(true) })
+	b.Publish("x", nil)
+	if !after.Load() {
+		t.Error("a panicking handler stopped the delivery to the next one")
+	}
+}
+
+func TestAsyncBufferingAndDrops(t *testing.T) {
+	const pattern = "test.buffer.*"
+	b := New()
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	var got []any
+	sub := b.SubscribeAsync(pattern, 2, func(e Event) {
+		if len(got) == 0 {
+			close(started)
+			<-gate
+		}
+		got = append(got, e.Data)
+	})
+	droppedBefore := eventsDropped.Value(pattern)
+
+	// The handler holds the first event, the buffer the next two; Publish
+	// never waits for it and drops the rest.
+	b.Publish("test.buffer.a", 1)
+	<-started
+	for i := 2; i <= 6; i++ {
+		b.Publish("test.buffer.a", i)
+	}
+	b.Publish("test.other.a", 0)
+	if n := eventsDropped.Value(pattern) - droppedBefore; n != 3 {
+		t.Errorf("%v events counted as dropped, want 3", n)
+	}
+	sub.mu.Lock()
+	// The first drop was logged, which resets the count.
+	if sub.dropped != 2 {
+		t.Errorf("%d drops awaiting a warning, want 2", sub.dropped)
+	}
+	sub.mu.Unlock()
+
+	close(gate)
+	sub.Unsubscribe()
+	b.Publish("test.buffer.a", 7)
+	sub.Wait()
+	if want := []any{1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("delivered %v, want %v", got, want)
+	}
+}
@@ -1,7 +1,11 @@
 // Package goforge provides the GoForge interface for goforge modules.
 package goforge
 
-import "github.com/spf13/cobra"
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+)
 
 // This file/package allows the goforge module to be used as a library.
 // It defines the GoForge interface which can be implemented by any module
@@ -24,6 +28,59 @@ type GoForge interface {
 	Module() string
 	// Execute runs the command and returns an error if it fails.
 	Execute() error
+	// ExecuteContext runs the command with ctx, which is cancelled when the
+	// execution should stop (e.g. on SIGINT), and returns an error if it fails.
+	ExecuteContext(ctx context.Context) error
 	// Command returns the cobra.Command associated with this module.
 	Command() *cobra.Command
 }
+
+// Lifecycle is an optional interface for modules that run as long-lived
+// services. Modules implementing it alongside GoForge are initialized,
+// started and stopped by the runtime behind the start command.
+type Lifecycle interface {
+	// Init prepares the module before any module is started.
+	Init() error
+	// Start runs the module. It either returns once the module is up,
+	// leaving any long-running work in goroutines bound to ctx, or blocks
+	// until ctx is done after calling Ready(ctx) once it is up. Dependent
+	// modules are started only after that; an error aborts the whole
+	// runtime.
+	Start(ctx context.Context) error
+	// Stop shuts the module down, returning before ctx's deadline.
+	Stop(ctx context.Context) error
+	// Health returns nil when the module is healthy.
+	Health() error
+}
+
+// Dependent is an optional interface for modules that need other modules
+// to be initialized and started before them.
+type Dependent interface {
+	// Dependencies returns the Module() names this module depends on.
+	Dependencies() []string
+}
+
+// Reloadable is an optional interface for modules that react to
+// configuration reloads without a restart.
+type Reloadable interface {
+	// Reload is called after a new configuration has been validated and
+	// applied, with the module's configuration section before and after.
+	Reload(prev, next map[string]any) error
+}
+
+type readyKey struct{}
+
+// WithReady returns a copy of ctx on which Ready calls fn. The runtime uses
+// it to learn when a module whose Start blocks is up.
+func WithReady(ctx context.Context, fn func()) context.Context {
+	return context.WithValue(ctx, readyKey{}, fn)
+}
+
+// Ready reports that the module started with ctx is up. A Start that blocks
+// until ctx is done must call it, or dependent modules never start. It does
+// nothing on contexts not created by WithReady.
+func Ready(ctx context.Context) {
+	if fn, ok := ctx.Value(readyKey{}).(func()); ok {
+		fn()
+	}
+}
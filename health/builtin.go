@@ -0,0 +1,94 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/rafa-mori/goforge/config"
+	manifest "github.com/rafa-mori/goforge/info"
+	"github.com/rafa-mori/goforge/paths"
+	vs "github.com/rafa-mori/goforge/version"
+)
+
+// ConfigKeyDiskMinFreeMB is the free space, in MiB, the disk check
+// requires in the data directory.
+const ConfigKeyDiskMinFreeMB = "health.disk_min_free_mb"
+
+func init() {
+	config.SetDefault(ConfigKeyDiskMinFreeMB, 100)
+
+	_ = Register(Check{Name: "disk", Kind: Readiness, Critical: true, Run: checkDisk})
+	_ = Register(Check{Name: "manifest", Kind: Both, Critical: true, Run: checkManifest})
+	_ = Register(Check{
+		Name:     "version",
+		Kind:     Readiness,
+		Timeout:  10 * time.Second,
+		CacheTTL: time.Hour,
+		Run:      checkVersion,
+	})
+}
+
+// checkDisk fails when the file system holding the data directory is
+// running out of space.
+func checkDisk(_ context.Context) error {
+	dir := existingParent(paths.DataDir())
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return fmt.Errorf("statfs %s: %w", dir, err)
+	}
+	free := st.Bavail * uint64(st.Bsize)
+	minFree := uint64(config.Get().GetInt(ConfigKeyDiskMinFreeMB)) << 20
+	if free < minFree {
+		return fmt.Errorf("only %d MiB free in %s, need %d MiB", free>>20, dir, minFree>>20)
+	}
+	return nil
+}
+
+// existingParent returns dir or its closest existing ancestor, since the
+// data directory is only created when first written to.
+func existingParent(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// checkManifest fails when the embedded manifest cannot be loaded.
+func checkManifest(_ context.Context) error {
+	info, err := manifest.GetManifest()
+	if err != nil {
+		return err
+	}
+	if info.GetName() == "" || info.GetVersion() == "" {
+		return errors.New("manifest has no name or version")
+	}
+	return nil
+}
+
+// checkVersion fails when a newer release is available.
+func checkVersion(ctx context.Context) error {
+	return checkVersionOf(ctx, vs.GetService())
+}
+
+func checkVersionOf(ctx context.Context, svc vs.Service) error {
+	latest, err := svc.IsLatestVersionContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !latest {
+		newest, _ := svc.GetLatestVersionContext(ctx)
+		return fmt.Errorf("running %s, latest release is %s", svc.GetCurrentVersion(), newest)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+// Package health runs the named liveness and readiness checks registered
+// by modules and aggregates their results into the reports served on
+// /healthz and /readyz and printed by the health command.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds a check registered without a timeout.
+const DefaultTimeout = 5 * time.Second
+
+// Kind says which probes a check takes part in.
+type Kind int
+
+const (
+	// Liveness checks fail when the process must be restarted.
+	Liveness Kind = 1 << iota
+	// Readiness checks fail when the process cannot take traffic.
+	Readiness
+	// Both makes a check take part in both probes.
+	Both = Liveness | Readiness
+)
+
+// ParseKind parses "live", "ready" or "all".
+func ParseKind(s string) (Kind, error) {
+	switch s {
+	case "live", "liveness":
+		return Liveness, nil
+	case "ready", "readiness":
+		return Readiness, nil
+	case "all", "":
+		return Both, nil
+	}
+	return 0, fmt.Errorf("unknown check kind %q, expected live, ready or all", s)
+}
+
+// Report statuses.
+const (
+	StatusOK = "ok"
+	// StatusDegraded means only non-critical checks failed.
+	StatusDegraded = "degraded"
+	// StatusFail means at least one critical check failed.
+	StatusFail = "fail"
+)
+
+// Check is a named health check.
+type Check struct {
+	Name string
+	Kind Kind
+	// Critical checks fail the whole report; the others only degrade it.
+	Critical bool
+	// Timeout bounds a run; DefaultTimeout is used when zero.
+	Timeout time.Duration
+	// CacheTTL reuses the last result for that long, for checks too
+	// expensive to run on every probe. Failures of critical checks are not
+	// cached, so a recovery is seen by the next probe.
+	CacheTTL time.Duration
+	// Run returns nil when healthy. It should return once ctx is done.
+	Run func(ctx context.Context) error
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Critical bool          `json:"critical"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Report aggregates the results of a run.
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Healthy reports whether no critical check failed.
+func (r *Report) Healthy() bool { return r.Status != StatusFail }
+
+// Add appends a result and updates the status of the report.
+func (r *Report) Add(res Result) {
+	r.Checks = append(r.Checks, res)
+	sort.Slice(r.Checks, func(i, j int) bool { return r.Checks[i].Name < r.Checks[j].Name })
+	if res.Status == StatusOK {
+		if r.Status == "" {
+			r.Status = StatusOK
+		}
+		return
+	}
+	if res.Critical {
+		r.Status = StatusFail
+	} else if r.Status != StatusFail {
+		r.Status = StatusDegraded
+	}
+}
+
+// AddErrors adds one critical result per entry of errs, such as the
+// health of the lifecycle modules.
+func (r *Report) AddErrors(errs map[string]error) {
+	for name, err := range errs {
+		r.Add(newResult(name, true, err, 0))
+	}
+}
+
+func newResult(name string, critical bool, err error, elapsed time.Duration) Result {
+	res := Result{Name: name, Status: StatusOK, Critical: critical, Duration: elapsed}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	return res
+}
+
+type entry struct {
+	check Check
+
+	// mu guards the cache and the run in progress, never a run itself.
+	mu       sync.Mutex
+	cached   Result
+	cachedAt time.Time
+	inflight *call
+}
+
+// call is a run of a check shared by the probes arriving while it runs, so
+// a slow or hung check never has more than one goroutine.
+type call struct {
+	started time.Time
+	done    chan struct{}
+	err     error
+}
+
+// Registry holds checks.
+type Registry struct {
+	mu     sync.RWMutex
+	checks map[string]*entry
+}
+
+// Default is the registry used by the package-level functions.
+var Default = NewRegistry()
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{checks: make(map[string]*entry)}
+}
+
+// Register adds c to the Default registry.
+func Register(c Check) error { return Default.Register(c) }
+
+// Run runs the checks of the given kind of the Default registry.
+func Run(ctx context.Context, kind Kind) *Report { return Default.Run(ctx, kind) }
+
+// Register adds c, replacing a check with the same name.
+func (r *Registry) Register(c Check) error {
+	if c.Name == "" {
+		return errors.New("check name is required")
+	}
+	if c.Run == nil {
+		return fmt.Errorf("check %s: Run is required", c.Name)
+	}
+	if c.Kind&Both == 0 {
+		return fmt.Errorf("check %s: Kind must include Liveness or Readiness", c.Name)
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[c.Name] = &entry{check: c}
+	return nil
+}
+
+// Unregister removes the named check.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.checks, name)
+}
+
+// Run runs every check taking part in kind concurrently and returns the
+// aggregated report. A report without checks is ok.
+func (r *Registry) Run(ctx context.Context, kind Kind) *Report {
+	r.mu.RLock()
+	entries := make([]*entry, 0, len(r.checks))
+	for _, e := range r.checks {
+		if e.check.Kind&kind != 0 {
+			entries = append(entries, e)
+		}
+	}
+	r.mu.RUnlock()
+
+	results := make([]Result, len(entries))
+	var wg sync.WaitGroup
+	for i, e := range entries {
+		wg.Add(1)
+		go func(i int, e *entry) {
+			defer wg.Done()
+			results[i] = e.run(ctx)
+		}(i, e)
+	}
+	wg.Wait()
+
+	report := &Report{Status: StatusOK}
+	for _, res := range results {
+		report.Add(res)
+	}
+	return report
+}
+
+// run returns the cached result when fresh, else waits at most the check
+// timeout for a run.
+func (e *entry) run(ctx context.Context) Result {
+	e.mu.Lock()
+	if e.check.CacheTTL > 0 && !e.cachedAt.IsZero() && time.Since(e.cachedAt) < e.check.CacheTTL {
+		res := e.cached
+		e.mu.Unlock()
+		return res
+	}
+	c := e.inflight
+	if c == nil {
+		c = &call{started: time.Now(), done: make(chan struct{})}
+		e.inflight = c
+		go e.exec(context.WithoutCancel(ctx), c)
+	}
+	e.mu.Unlock()
+
+	timer := time.NewTimer(e.check.Timeout - time.Since(c.started))
+	defer timer.Stop()
+	select {
+	case <-c.done:
+		return newResult(e.check.Name, e.check.Critical, c.err, time.Since(c.started))
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	err := fmt.Errorf("timed out after %s", e.check.Timeout)
+	return newResult(e.check.Name, e.check.Critical, err, time.Since(c.started))
+}
+
+// exec runs the check for c, bounded by the check timeout, and caches its
+// result unless a critical check failed. A check ignoring its context keeps its goroutine until it
+// returns, but the probes meanwhile wait on that same run.
+func (e *entry) exec(ctx context.Context, c *call) {
+	ctx, cancel := context.WithTimeout(ctx, e.check.Timeout)
+	defer cancel()
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				c.err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		c.err = e.check.Run(ctx)
+	}()
+
+	e.mu.Lock()
+	if c.err == nil || !e.check.Critical {
+		e.cached = newResult(e.check.Name, e.check.Critical, c.err, time.Since(c.started))
+		e.cachedAt = time.Now()
+	}
+	e.inflight = nil
+	e.mu.Unlock()
+	close(c.done)
+}
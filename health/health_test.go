@@ -0,0 +1,182 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rafa-mori/goforge/events"
+	vs "github.com/rafa-mori/goforge/version"
+)
+
+func TestReportStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []Result
+		want    string
+	}{
+		{"no checks", nil, StatusOK},
+		{"all ok", []Result{newResult("a", true, nil, 0), newResult("b", false, nil, 0)}, StatusOK},
+		{"non-critical failure", []Result{newResult("a", true, nil, 0), newResult("b", false, errors.New("x"), 0)}, StatusDegraded},
+		{"critical failure", []Result{newResult("a", true, errors.New("x"), 0), newResult("b", false, errors.New("x"), 0)}, StatusFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Report{Status: StatusOK}
+			for _, res := range tt.results {
+				r.Add(res)
+			}
+			if r.Status != tt.want {
+				t.Errorf("Status = %s, want %s", r.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	tests := []struct {
+		name     string
+		critical bool
+		err      error
+		wantRuns int64
+	}{
+		{"success is cached", true, nil, 1},
+		{"non-critical failure is cached", false, errors.New("outdated"), 1},
+		{"critical failure is not cached", true, errors.New("down"), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := NewRegistry()
+			var runs atomic.Int64
+			if err := reg.Register(Check{Name: "c", Kind: Both, Critical: tt.critical, CacheTTL: time.Hour, Run: func(context.Context) error {
+				runs.Add(1)
+				return tt.err
+			}}); err != nil {
+				t.Fatal(err)
+			}
+			first := reg.Run(context.Background(), Both)
+			second := reg.Run(context.Background(), Both)
+			if got := runs.Load(); got != tt.wantRuns {
+				t.Errorf("runs = %d, want %d", got, tt.wantRuns)
+			}
+			if first.Status != second.Status {
+				t.Errorf("cached status %s differs from %s", second.Status, first.Status)
+			}
+		})
+	}
+}
+
+func TestVersionCheckProbedTwice(t *testing.T) {
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"name":"v2.0.0"}]`))
+	}))
+	defer srv.Close()
+
+	var announced atomic.Int64
+	sub := events.Subscribe(events.TopicVersionAvailable, func(events.Event) { announced.Add(1) })
+	defer sub.Unsubscribe()
+
+	svc := vs.NewService(srv.URL, "1.0.0")
+	reg := NewRegistry()
+	if err := reg.Register(Check{Name: "version", Kind: Readiness, CacheTTL: time.Hour, Run: func(ctx context.Context) error {
+		return checkVersionOf(ctx, svc)
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if r := reg.Run(context.Background(), Readiness); r.Status != StatusDegraded {
+			t.Fatalf("probe %d status = %s, want %s", i, r.Status, StatusDegraded)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("%d HTTP calls, want 1", got)
+	}
+	if got := announced.Load(); got != 1 {
+		t.Errorf("%d events, want 1", got)
+	}
+
+	// Checking again past the cache does not announce the same version.
+	if _, err := svc.IsLatestVersionContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := announced.Load(); got != 1 {
+		t.Errorf("%d events after a new check of the same version, want 1", got)
+	}
+}
+
+func TestHungCheckRunsOnce(t *testing.T) {
+	reg := NewRegistry()
+	var runs atomic.Int64
+	release := make(chan struct{})
+	defer close(release)
+	if err := reg.Register(Check{Name: "hung", Kind: Both, Critical: true, Timeout: 20 * time.Millisecond, Run: func(context.Context) error {
+		runs.Add(1)
+		<-release // ignores its context
+		return nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if r := reg.Run(context.Background(), Both); r.Status != StatusFail {
+				t.Errorf("Status = %s, want %s", r.Status, StatusFail)
+			}
+		}()
+	}
+	wg.Wait()
+	if r := reg.Run(context.Background(), Both); r.Status != StatusFail {
+		t.Errorf("Status after timeout = %s, want %s", r.Status, StatusFail)
+	}
+	if got := runs.Load(); got != 1 {
+		t.Errorf("runs = %d, want 1 while the first run is stuck", got)
+	}
+}
+
+func TestSlowCheckDoesNotBlockCache(t *testing.T) {
+	reg := NewRegistry()
+	started, release := make(chan struct{}), make(chan struct{})
+	var first atomic.Bool
+	if err := reg.Register(Check{Name: "slow", Kind: Both, CacheTTL: time.Hour, Run: func(context.Context) error {
+		if first.CompareAndSwap(false, true) {
+			close(started)
+			<-release
+		}
+		return nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reg.Run(context.Background(), Both)
+	}()
+	<-started
+
+	// The lock is not held during the run, so the cache stays readable.
+	e := reg.checks["slow"]
+	locked := make(chan struct{})
+	go func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Error("entry lock held while the check runs")
+	}
+	close(release)
+	<-done
+}
@@ -28,6 +28,12 @@ type manifest struct {
 	Keywords        []string `json:"keywords,omitempty"`
 	Platforms       []string `json:"platforms,omitempty"`
 	LogLevel        string   `json:"log_level,omitempty"`
+	LogDir          string   `json:"log_dir,omitempty"`
+	LogFile         string   `json:"log_file,omitempty"`
+	LogMaxSizeMB    int      `json:"log_max_size_mb,omitempty"`
+	LogMaxAge       string   `json:"log_max_age,omitempty"`
+	LogMaxBackups   int      `json:"log_max_backups,omitempty"`
+	LogCompress     *bool    `json:"log_compress,omitempty"`
 	Debug           bool     `json:"debug,omitempty"`
 	ShowTrace       bool     `json:"show_trace,omitempty"`
 	Private         bool     `json:"private,omitempty"`
@@ -45,6 +51,15 @@ type Manifest interface {
 	GetLicense() string
 	GetKeywords() []string
 	GetPlatforms() []string
+	GetLogLevel() string
+	GetLogDir() string
+	GetLogFile() string
+	GetLogMaxSizeMB() int
+	GetLogMaxAge() string
+	GetLogMaxBackups() int
+	IsLogCompress() bool
+	IsDebug() bool
+	IsShowTrace() bool
 	IsPrivate() bool
 }
 
@@ -60,6 +75,15 @@ func (m *manifest) GetAuthor() string      { return m.Author }
 func (m *manifest) GetLicense() string     { return m.License }
 func (m *manifest) GetKeywords() []string  { return m.Keywords }
 func (m *manifest) GetPlatforms() []string { return m.Platforms }
+func (m *manifest) GetLogLevel() string    { return m.LogLevel }
+func (m *manifest) GetLogDir() string      { return m.LogDir }
+func (m *manifest) GetLogFile() string     { return m.LogFile }
+func (m *manifest) GetLogMaxSizeMB() int   { return m.LogMaxSizeMB }
+func (m *manifest) GetLogMaxAge() string   { return m.LogMaxAge }
+func (m *manifest) GetLogMaxBackups() int  { return m.LogMaxBackups }
+func (m *manifest) IsLogCompress() bool    { return m.LogCompress == nil || *m.LogCompress }
+func (m *manifest) IsDebug() bool          { return m.Debug }
+func (m *manifest) IsShowTrace() bool      { return m.ShowTrace }
 func (m *manifest) IsPrivate() bool        { return m.Private }
 
 func init() {
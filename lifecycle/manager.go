@@ -0,0 +1,355 @@
+// Package lifecycle runs long-lived GoForge modules: it initializes and
+// starts them in dependency order, waits for a termination signal and shuts
+// them down in reverse order within a deadline.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rafa-mori/goforge"
+	"github.com/rafa-mori/goforge/events"
+	gl "github.com/rafa-mori/goforge/logger"
+	"github.com/rafa-mori/goforge/registry"
+)
+
+// DefaultShutdownTimeout is the deadline given to all units to stop.
+const DefaultShutdownTimeout = 15 * time.Second
+
+// readyWarningDelay is how long a unit may take to come up before a warning
+// is logged.
+const readyWarningDelay = 30 * time.Second
+
+type unit struct {
+	name string
+	deps []string
+	lc   goforge.Lifecycle
+}
+
+// Manager owns a set of lifecycle units and drives them through
+// Init, Start and Stop.
+type Manager struct {
+	mu              sync.Mutex
+	units           []unit
+	started         []unit
+	shutdownTimeout time.Duration
+	onReady         []func()
+	onStopping      []func()
+	// starts tracks the Start calls still running.
+	starts sync.WaitGroup
+}
+
+// NewManager returns an empty Manager. A non-positive shutdownTimeout
+// falls back to DefaultShutdownTimeout.
+func NewManager(shutdownTimeout time.Duration) *Manager {
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = DefaultShutdownTimeout
+	}
+	return &Manager{shutdownTimeout: shutdownTimeout}
+}
+
+// Add appends a unit to the manager. When lc implements goforge.Dependent
+// its dependencies are honored when ordering startup and shutdown.
+func (m *Manager) Add(name string, lc goforge.Lifecycle) {
+	var deps []string
+	if d, ok := lc.(goforge.Dependent); ok {
+		deps = d.Dependencies()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.units = append(m.units, unit{name: name, deps: deps, lc: lc})
+}
+
+// AddActiveModules adds every active registered module that implements
+// goforge.Lifecycle.
+func (m *Manager) AddActiveModules() {
+	for _, mod := range registry.ActiveModules() {
+		if lc, ok := mod.(goforge.Lifecycle); ok {
+			m.Add(mod.Module(), lc)
+		}
+	}
+}
+
+// OnReady registers fn to run once every unit has started.
+func (m *Manager) OnReady(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onReady = append(m.onReady, fn)
+}
+
+// OnStopping registers fn to run when shutdown begins, before any unit is
+// stopped.
+func (m *Manager) OnStopping(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onStopping = append(m.onStopping, fn)
+}
+
+// Healthy reports whether every unit's Health check passes.
+func (m *Manager) Healthy() bool {
+	for _, err := range m.Health() {
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Names returns the names of the managed units.
+func (m *Manager) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.units))
+	for _, u := range m.units {
+		names = append(names, u.name)
+	}
+	return names
+}
+
+// Health returns the result of every unit's Health check, keyed by name.
+func (m *Manager) Health() map[string]error {
+	m.mu.Lock()
+	units := append([]unit(nil), m.units...)
+	m.mu.Unlock()
+
+	results := make(map[string]error, len(units))
+	for _, u := range units {
+		results[u.name] = u.lc.Health()
+	}
+	return results
+}
+
+// Graph returns the dependency graph of the managed units. Dependencies on
+// active registered modules that have no lifecycle are satisfied by
+// definition and left out.
+func (m *Manager) Graph() *registry.Graph {
+	m.mu.Lock()
+	units := append([]unit(nil), m.units...)
+	m.mu.Unlock()
+
+	known := make(map[string]bool, len(units))
+	for _, u := range units {
+		known[u.name] = true
+	}
+	names := make([]string, 0, len(units))
+	deps := make(map[string][]string, len(units))
+	for _, u := range units {
+		names = append(names, u.name)
+		for _, dep := range u.deps {
+			if !known[dep] {
+				if mod, ok := registry.Lookup(dep); ok && mod.Active() {
+					continue
+				}
+			}
+			deps[u.name] = append(deps[u.name], dep)
+		}
+	}
+	return registry.NewGraph(names, deps)
+}
+
+// Run initializes and starts every unit in dependency order, then blocks
+// until ctx is done or SIGINT or SIGTERM is received. All started units
+// are then stopped in reverse order.
+func (m *Manager) Run(ctx context.Context) error {
+	levels, err := m.Graph().Levels()
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]unit)
+	m.mu.Lock()
+	for _, u := range m.units {
+		byName[u.name] = u
+	}
+	m.mu.Unlock()
+
+	if len(byName) == 0 {
+		gl.Log("warn", "No lifecycle modules registered, waiting for a termination signal")
+	}
+
+	for _, level := range levels {
+		for _, name := range level {
+			gl.Log("debug", "Initializing "+name)
+			if err := byName[name].lc.Init(); err != nil {
+				return fmt.Errorf("init %s: %w", name, err)
+			}
+		}
+	}
+
+	sigCtx, stopSignals := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	runCtx, cancel := context.WithCancelCause(sigCtx)
+	defer cancel(nil)
+
+	for _, level := range levels {
+		if err := m.startLevel(runCtx, cancel, level, byName); err != nil {
+			cancel(err)
+			break
+		}
+		if runCtx.Err() != nil {
+			break
+		}
+	}
+	if runCtx.Err() == nil {
+		m.runHooks(m.onReady)
+		<-runCtx.Done()
+	}
+
+	cause := context.Cause(runCtx)
+	if sigCtx.Err() != nil {
+		gl.Log("info", "Termination requested, shutting down")
+		cause = nil
+	}
+	m.runHooks(m.onStopping)
+	return errors.Join(cause, m.stop(), m.waitStarts())
+}
+
+func (m *Manager) runHooks(hooks []func()) {
+	m.mu.Lock()
+	list := append([]func(){}, hooks...)
+	m.mu.Unlock()
+	for _, fn := range list {
+		fn()
+	}
+}
+
+// startLevel starts every unit of a wave concurrently and waits for all of
+// them to come up.
+func (m *Manager) startLevel(ctx context.Context, fail context.CancelCauseFunc, level []string, byName map[string]unit) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(level))
+	for i, name := range level {
+		wg.Add(1)
+		go func(i int, u unit) {
+			defer wg.Done()
+			errs[i] = m.startUnit(ctx, fail, u)
+		}(i, byName[name])
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// startUnit calls Start in its own goroutine and waits until the unit is up:
+// Start returned nil or called goforge.Ready. A Start that keeps blocking
+// runs until ctx is done, and a later failure aborts the runtime through
+// fail. A unit still starting when ctx is done counts as started, so that
+// it is stopped with the others.
+func (m *Manager) startUnit(ctx context.Context, fail context.CancelCauseFunc, u unit) error {
+	gl.Log("info", "Starting "+u.name)
+
+	var (
+		mu     sync.Mutex
+		up     bool
+		ready  = make(chan struct{})
+		result = make(chan error, 1)
+	)
+	markReady := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if !up {
+			up = true
+			close(ready)
+		}
+	}
+
+	// Lines logged through the context are filtered by the level of the
+	// module.
+	startCtx := goforge.WithReady(gl.IntoContext(ctx, gl.ForModule(u.name)), markReady)
+	m.starts.Add(1)
+	go func() {
+		defer m.starts.Done()
+		err := u.lc.Start(startCtx)
+		mu.Lock()
+		wasUp := up
+		up = true
+		mu.Unlock()
+		if !wasUp {
+			result <- err
+			return
+		}
+		if err != nil && ctx.Err() == nil {
+			gl.Log("error", "Module "+u.name+" failed: "+err.Error())
+			fail(fmt.Errorf("module %s: %w", u.name, err))
+		}
+	}()
+
+	warn := time.NewTimer(readyWarningDelay)
+	defer warn.Stop()
+wait:
+	for {
+		select {
+		case err := <-result:
+			if err != nil {
+				gl.Log("error", "Module "+u.name+" failed to start: "+err.Error())
+				return fmt.Errorf("start %s: %w", u.name, err)
+			}
+			break wait
+		case <-ready:
+			break wait
+		case <-ctx.Done():
+			m.mu.Lock()
+			m.started = append(m.started, u)
+			m.mu.Unlock()
+			return nil
+		case <-warn.C:
+			gl.Log("warn", "Module "+u.name+" is not up after "+readyWarningDelay.String()+"; a Start that blocks must call goforge.Ready")
+		}
+	}
+
+	m.mu.Lock()
+	m.started = append(m.started, u)
+	m.mu.Unlock()
+	events.Publish(events.TopicModuleStarted, events.ModuleEvent{Name: u.name})
+	return nil
+}
+
+// waitStarts waits for the Start calls still blocking to return once their
+// context is done, within the shutdown timeout.
+func (m *Manager) waitStarts() error {
+	done := make(chan struct{})
+	go func() {
+		m.starts.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-time.After(m.shutdownTimeout):
+		return errors.New("modules did not return from Start after shutdown")
+	}
+}
+
+// stop stops every started unit in reverse start order, sharing one
+// deadline.
+func (m *Manager) stop() error {
+	m.mu.Lock()
+	started := m.started
+	m.started = nil
+	m.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		u := started[i]
+		gl.Log("info", "Stopping "+u.name)
+		ev := events.ModuleEvent{Name: u.name}
+		if err := u.lc.Stop(gl.IntoContext(ctx, gl.ForModule(u.name))); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s: %w", u.name, err))
+			gl.Log("error", "Failed to stop "+u.name+": "+err.Error())
+			ev.Error = err.Error()
+		}
+		events.Publish(events.TopicModuleStopped, ev)
+		if ctx.Err() != nil {
+			errs = append(errs, fmt.Errorf("shutdown deadline of %s exceeded", m.shutdownTimeout))
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
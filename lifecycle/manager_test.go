@@ -0,0 +1,171 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rafa-mori/goforge"
+)
+
+// recorder collects the calls made to fake units, in order.
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) add(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.calls...)
+}
+
+// fakeUnit is a lifecycle unit whose Start behaviour is set per test.
+type fakeUnit struct {
+	name  string
+	deps  []string
+	rec   *recorder
+	start func(ctx context.Context) error
+}
+
+func (u *fakeUnit) Init() error            { return nil }
+func (u *fakeUnit) Health() error          { return nil }
+func (u *fakeUnit) Dependencies() []string { return u.deps }
+func (u *fakeUnit) Stop(context.Context) error {
+	u.rec.add("stop " + u.name)
+	return nil
+}
+func (u *fakeUnit) Start(ctx context.Context) error {
+	u.rec.add("start " + u.name)
+	if u.start == nil {
+		return nil
+	}
+	return u.start(ctx)
+}
+
+// blockingReady blocks until ctx is done after reporting ready.
+func blockingReady(ctx context.Context) error {
+	goforge.Ready(ctx)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// blockingSilent blocks until ctx is done without reporting ready.
+func blockingSilent(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestManagerRun(t *testing.T) {
+	bootErr := errors.New("boom")
+	tests := []struct {
+		name      string
+		units     func(rec *recorder) []*fakeUnit
+		cancel    bool // cancel the context once every unit is up
+		wantCalls []string
+		wantErr   string
+	}{
+		{
+			name: "returning starts",
+			units: func(rec *recorder) []*fakeUnit {
+				return []*fakeUnit{
+					{name: "http", deps: []string{"db"}, rec: rec},
+					{name: "db", rec: rec},
+				}
+			},
+			cancel:    true,
+			wantCalls: []string{"start db", "start http", "stop http", "stop db"},
+		},
+		{
+			name: "blocking start reporting ready",
+			units: func(rec *recorder) []*fakeUnit {
+				return []*fakeUnit{
+					{name: "http", deps: []string{"db"}, rec: rec},
+					{name: "db", rec: rec, start: blockingReady},
+				}
+			},
+			cancel:    true,
+			wantCalls: []string{"start db", "start http", "stop http", "stop db"},
+		},
+		{
+			name: "blocking start never ready is still stopped",
+			units: func(rec *recorder) []*fakeUnit {
+				return []*fakeUnit{
+					{name: "http", deps: []string{"db"}, rec: rec},
+					{name: "db", rec: rec, start: blockingSilent},
+				}
+			},
+			wantCalls: []string{"start db", "stop db"},
+		},
+		{
+			name: "start error stops the started units",
+			units: func(rec *recorder) []*fakeUnit {
+				return []*fakeUnit{
+					{name: "http", deps: []string{"db"}, rec: rec, start: func(context.Context) error { return bootErr }},
+					{name: "db", rec: rec, start: blockingReady},
+				}
+			},
+			wantCalls: []string{"start db", "start http", "stop db"},
+			wantErr:   "start http: boom",
+		},
+		{
+			name: "failure after ready aborts the runtime",
+			units: func(rec *recorder) []*fakeUnit {
+				return []*fakeUnit{
+					{name: "db", rec: rec, start: func(ctx context.Context) error {
+						goforge.Ready(ctx)
+						return bootErr
+					}},
+				}
+			},
+			wantCalls: []string{"start db", "stop db"},
+			wantErr:   "module db: boom",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			m := NewManager(time.Second)
+			for _, u := range tt.units(rec) {
+				m.Add(u.name, u)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				m.OnReady(cancel)
+			} else if tt.wantErr == "" {
+				// The unit never comes up; shut down while it is starting.
+				time.AfterFunc(50*time.Millisecond, cancel)
+			}
+
+			errc := make(chan error, 1)
+			go func() { errc <- m.Run(ctx) }()
+			var err error
+			select {
+			case err = <-errc:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Run did not return")
+			}
+
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Run() error = %v, want %q", err, tt.wantErr)
+			}
+			if got := rec.list(); !reflect.DeepEqual(got, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", got, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rafa-mori/goforge/metrics"
+	lz "github.com/rafa-mori/logz/logger"
+)
+
+// OverflowPolicy is what an async logger does with a line when its buffer
+// is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock makes the caller wait for room in the buffer.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropNewest discards the line being logged.
+	OverflowDropNewest OverflowPolicy = "drop_newest"
+	// OverflowDropOldest discards the oldest buffered line to make room.
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+)
+
+// DefaultBufferSize is the buffer size of the async mode when none is set.
+const DefaultBufferSize = 1024
+
+// ParseOverflowPolicy returns the policy named by s (case-insensitive).
+func ParseOverflowPolicy(s string) (OverflowPolicy, bool) {
+	switch p := OverflowPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case OverflowBlock, OverflowDropNewest, OverflowDropOldest:
+		return p, true
+	case "":
+		return OverflowBlock, true
+	}
+	return OverflowBlock, false
+}
+
+// AsyncOptions configures the async mode.
+type AsyncOptions struct {
+	// BufferSize is how many lines wait to be written. Zero or less
+	// disables the async mode.
+	BufferSize int
+	// Overflow is applied when the buffer is full.
+	Overflow OverflowPolicy
+}
+
+// logDropped counts the lines discarded by a full buffer.
+var logDropped = metrics.NewCounter(metrics.Name("log_dropped_total"), "Log messages dropped by a full async buffer, by overflow policy.", "policy")
+
+var dropped atomic.Uint64
+
+// Dropped returns how many lines the async mode has discarded.
+func Dropped() uint64 { return dropped.Load() }
+
+// asyncWriter queues entries in a ring buffer written by one goroutine, so
+// loggers only pay for the copy. Fatal entries are written synchronously
+// after the buffer is drained, since the process exits right after them.
+type asyncWriter struct {
+	next   interface{ Write(entry any) error }
+	policy OverflowPolicy
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	buf     []any
+	head    int
+	count   int
+	queued  uint64 // entries ever queued
+	settled uint64 // entries written or dropped from the buffer
+	closed  bool
+	done    chan struct{}
+}
+
+func newAsyncWriter(next interface{ Write(entry any) error }, opts AsyncOptions) *asyncWriter {
+	w := &asyncWriter{
+		next:   next,
+		policy: opts.Overflow,
+		buf:    make([]any, opts.BufferSize),
+		done:   make(chan struct{}),
+	}
+	w.cond = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+func (w *asyncWriter) Write(entry any) error {
+	if e, ok := entry.(lz.LogzEntry); ok && e.GetLevel() == "FATAL" {
+		w.Flush()
+		return w.next.Write(entry)
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return w.next.Write(entry)
+	}
+	if w.count == len(w.buf) {
+		switch w.policy {
+		case OverflowDropNewest:
+			w.mu.Unlock()
+			w.drop()
+			return nil
+		case OverflowDropOldest:
+			w.buf[w.head] = nil
+			w.head = (w.head + 1) % len(w.buf)
+			w.count--
+			w.settled++
+			w.drop()
+		default:
+			for w.count == len(w.buf) && !w.closed {
+				w.cond.Wait()
+			}
+			if w.closed {
+				w.mu.Unlock()
+				return w.next.Write(entry)
+			}
+		}
+	}
+	w.buf[(w.head+w.count)%len(w.buf)] = entry
+	w.count++
+	w.queued++
+	w.cond.Broadcast()
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *asyncWriter) drop() {
+	dropped.Add(1)
+	logDropped.Inc(string(w.policy))
+}
+
+// run writes the buffered entries until the writer is closed and drained.
+func (w *asyncWriter) run() {
+	defer close(w.done)
+	batch := make([]any, 0, len(w.buf))
+	for {
+		w.mu.Lock()
+		for w.count == 0 && !w.closed {
+			w.cond.Wait()
+		}
+		if w.count == 0 {
+			w.mu.Unlock()
+			return
+		}
+		for ; w.count > 0; w.count-- {
+			batch = append(batch, w.buf[w.head])
+			w.buf[w.head] = nil
+			w.head = (w.head + 1) % len(w.buf)
+		}
+		w.cond.Broadcast()
+		w.mu.Unlock()
+
+		for i, e := range batch {
+			if err := w.next.Write(e); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to write log entry: %v\n", err)
+			}
+			batch[i] = nil
+		}
+		w.mu.Lock()
+		w.settled += uint64(len(batch))
+		w.cond.Broadcast()
+		w.mu.Unlock()
+		batch = batch[:0]
+	}
+}
+
+// Flush waits until every entry queued so far is written.
+func (w *asyncWriter) Flush() {
+	w.mu.Lock()
+	target := w.queued
+	for w.settled < target && !w.closed {
+		w.cond.Wait()
+	}
+	closed := w.closed
+	w.mu.Unlock()
+	if closed {
+		<-w.done
+	}
+}
+
+// Close writes the remaining entries and stops the writer. Later entries
+// are written synchronously.
+func (w *asyncWriter) Close() {
+	w.mu.Lock()
+	w.closed = true
+	w.cond.Broadcast()
+	w.mu.Unlock()
+	<-w.done
+}
+
+// SetAsync switches the output to the async mode with opts, or back to
+// synchronous writes when opts.BufferSize is zero. Lines still buffered by
+// a previous mode are written first.
+func SetAsync(opts AsyncOptions) error {
+	policy, ok := ParseOverflowPolicy(string(opts.Overflow))
+	if !ok {
+		return fmt.Errorf("unknown overflow policy %q (want block, drop_newest or drop_oldest)", opts.Overflow)
+	}
+	opts.Overflow = policy
+	if opts.BufferSize < 0 {
+		opts.BufferSize = 0
+	}
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	if opts != asyncOpts {
+		asyncOpts = opts
+		applyOutput()
+	}
+	return nil
+}
+
+// Flush waits until every line logged so far is written. It returns at
+// once in synchronous mode.
+func Flush() {
+	outputMu.Lock()
+	w := asyncOut
+	outputMu.Unlock()
+	if w != nil {
+		w.Flush()
+	}
+}
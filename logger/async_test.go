@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"io"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// gatedWriter records entries, blocking until its gate is opened.
+type gatedWriter struct {
+	gate chan struct{}
+
+	mu      sync.Mutex
+	entries []any
+}
+
+func (w *gatedWriter) Write(entry any) error {
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.entries = append(w.entries, entry)
+	return nil
+}
+
+func (w *gatedWriter) written() []any {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]any(nil), w.entries...)
+}
+
+func TestAsyncOverflow(t *testing.T) {
+	tests := []struct {
+		policy      OverflowPolicy
+		wantDropped uint64
+		// wantFirst and wantLast bound the entries written, which are the
+		// ints 0 to 9 in order.
+		wantFirst, wantLast int
+	}{
+		{OverflowDropNewest, 6, 0, 3},
+		{OverflowDropOldest, 6, 0, 9},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			next := &gatedWriter{gate: make(chan struct{})}
+			w := newAsyncWriter(next, AsyncOptions{BufferSize: 3, Overflow: tt.policy})
+			defer w.Close()
+
+			before := Dropped()
+			// The first entry is taken by the writer goroutine, which then
+			// blocks on the gate, so the buffer holds three of the rest.
+			_ = w.Write(0)
+			waitTaken(w)
+			for i := 1; i < 10; i++ {
+				_ = w.Write(i)
+			}
+			if got := Dropped() - before; got != tt.wantDropped {
+				t.Errorf("dropped %d entries, want %d", got, tt.wantDropped)
+			}
+
+			close(next.gate)
+			w.Flush()
+			got := next.written()
+			if len(got) != 4 {
+				t.Fatalf("Flush returned with %d entries written, want 4: %v", len(got), got)
+			}
+			if got[0] != tt.wantFirst || got[len(got)-1] != tt.wantLast {
+				t.Errorf("written %v, want %d to %d", got, tt.wantFirst, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestAsyncBlockKeepsEverything(t *testing.T) {
+	next := &gatedWriter{gate: make(chan struct{})}
+	w := newAsyncWriter(next, AsyncOptions{BufferSize: 2, Overflow: OverflowBlock})
+	before := Dropped()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			_ = w.Write(i)
+		}
+	}()
+	close(next.gate)
+	<-done
+	w.Flush()
+	if got := len(next.written()); got != 20 {
+		t.Errorf("Flush returned with %d entries written, want 20", got)
+	}
+	if got := Dropped() - before; got != 0 {
+		t.Errorf("dropped %d entries with the block policy", got)
+	}
+
+	// Entries written after Close go straight through.
+	w.Close()
+	_ = w.Write(20)
+	if got := len(next.written()); got != 21 {
+		t.Errorf("%d entries written after Close, want 21", got)
+	}
+}
+
+// waitTaken waits until the writer goroutine took the buffered entries.
+func waitTaken(w *asyncWriter) {
+	for {
+		w.mu.Lock()
+		empty := w.count == 0
+		w.mu.Unlock()
+		if empty {
+			return
+		}
+		runtime.Gosched()
+	}
+}
+
+// benchmarkLog logs through the global logger into io.Discard, in JSON,
+// with opts.
+func benchmarkLog(b *testing.B, opts AsyncOptions) {
+	outputMu.Lock()
+	var w interface{ Write(entry any) error } = &entryWriter{out: io.Discard, formatter: jsonFormatter{}}
+	var async *asyncWriter
+	if opts.BufferSize > 0 {
+		async = newAsyncWriter(w, opts)
+		w = async
+	}
+	g.SetWriter(w)
+	outputMu.Unlock()
+	b.Cleanup(func() {
+		if async != nil {
+			async.Close()
+		}
+		outputMu.Lock()
+		applyOutput()
+		outputMu.Unlock()
+	})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			LogKV("error", "Benchmark line", "i", i, "job", "backup")
+			i++
+		}
+	})
+	if async != nil {
+		async.Flush()
+	}
+}
+
+func BenchmarkLogSync(b *testing.B) {
+	benchmarkLog(b, AsyncOptions{})
+}
+
+func BenchmarkLogAsyncBlock(b *testing.B) {
+	benchmarkLog(b, AsyncOptions{BufferSize: DefaultBufferSize, Overflow: OverflowBlock})
+}
+
+func BenchmarkLogAsyncDropNewest(b *testing.B) {
+	benchmarkLog(b, AsyncOptions{BufferSize: DefaultBufferSize, Overflow: OverflowDropNewest})
+}
+
+func BenchmarkLogAsyncDropOldest(b *testing.B) {
+	benchmarkLog(b, AsyncOptions{BufferSize: DefaultBufferSize, Overflow: OverflowDropOldest})
+}
@@ -0,0 +1,235 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	lz "github.com/rafa-mori/logz/logger"
+)
+
+// Format is the rendering of log entries.
+type Format string
+
+const (
+	// FormatText is the colored, human-oriented output of logz.
+	FormatText Format = "text"
+	// FormatJSON writes one JSON object per line.
+	FormatJSON Format = "json"
+	// FormatLogfmt writes one line of key=value pairs per entry.
+	FormatLogfmt Format = "logfmt"
+)
+
+// ParseFormat returns the Format named by s (case-insensitive).
+func ParseFormat(s string) (Format, bool) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case FormatText, FormatJSON, FormatLogfmt:
+		return f, true
+	case "":
+		return FormatText, true
+	}
+	return FormatText, false
+}
+
+// Field names of the JSON and logfmt formats. They are part of the output
+// contract: log pipelines parse them, so they must not change.
+const (
+	FieldTime    = "time"
+	FieldLevel   = "level"
+	FieldMessage = "msg"
+	FieldApp     = "app"
+	FieldBin     = "bin"
+	FieldVersion = "version"
+	FieldFunc    = "func"
+	FieldFile    = "file"
+	FieldLine    = "line"
+)
+
+// metadataFields maps the keys of the context map built by Log to the
+// stable field names. Keys mapped to "" only steer the text formatter and
+// are left out.
+var metadataFields = map[string]string{
+	"appName":       FieldApp,
+	"bin":           FieldBin,
+	"version":       FieldVersion,
+	"context":       FieldFunc,
+	"file":          FieldFile,
+	"line":          FieldLine,
+	"logType":       "",
+	"timestamp":     "",
+	"showData":      "",
+	"showContext":   "",
+	"showTimestamp": "",
+}
+
+// fixedFields is the order of the leading fields of every entry.
+var fixedFields = []string{FieldTime, FieldLevel, FieldMessage, FieldApp, FieldBin, FieldVersion, FieldFunc, FieldFile, FieldLine}
+
+func isFixedField(key string) bool {
+	for _, k := range fixedFields {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+type field struct {
+	key   string
+	value any
+}
+
+// entryFields returns the fields of e: the fixed fields first, in a stable
+// order, then any other metadata sorted by key. Metadata whose key clashes
+// with a fixed field is renamed with an "extra." prefix.
+func entryFields(e lz.LogzEntry) []field {
+	fixed := map[string]any{
+		FieldTime:    e.GetTimestamp().Format(time.RFC3339Nano),
+		FieldLevel:   strings.ToLower(string(e.GetLevel())),
+		FieldMessage: e.GetMessage(),
+	}
+	extra := map[string]any{}
+	for k, v := range e.GetMetadata() {
+		if name, known := metadataFields[k]; known {
+			if name != "" {
+				fixed[name] = v
+			}
+			continue
+		}
+		extra[k] = v
+	}
+
+	fields := make([]field, 0, len(fixedFields)+len(extra))
+	for _, k := range fixedFields {
+		if v, ok := fixed[k]; ok {
+			fields = append(fields, field{k, v})
+		}
+	}
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := k
+		if _, clash := fixed[k]; clash {
+			name = "extra." + k
+		}
+		fields = append(fields, field{name, extra[k]})
+	}
+	return fields
+}
+
+// jsonFormatter renders entries as JSON objects. Strings are escaped by
+// encoding/json, which turns newlines into \n and invalid UTF-8 into
+// U+FFFD, so every line is valid JSON.
+type jsonFormatter struct{}
+
+func (jsonFormatter) Format(e lz.LogzEntry) (string, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range entryFields(e) {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(jsonValue(f.value))
+	}
+	buf.WriteByte('}')
+	return buf.String(), nil
+}
+
+func jsonValue(v any) []byte {
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+	return data
+}
+
+// logfmtFormatter renders entries as key=value pairs. Values with spaces,
+// quotes, equal signs or control characters are quoted and escaped, and
+// those characters are replaced by underscores in keys, keeping every entry
+// on one line and parseable.
+type logfmtFormatter struct{}
+
+func (logfmtFormatter) Format(e lz.LogzEntry) (string, error) {
+	var b strings.Builder
+	for i, f := range entryFields(e) {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(logfmtKey(f.key))
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(f.value))
+	}
+	return b.String(), nil
+}
+
+// logfmtKey returns key with the characters logfmt keys cannot hold
+// replaced by underscores. Keys are never quoted, since most parsers do not
+// accept it.
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	if !needsQuoting(key) {
+		return key
+	}
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+func logfmtValue(v any) string {
+	var s string
+	switch x := v.(type) {
+	case string:
+		s = x
+	case error:
+		s = x.Error()
+	case fmt.Stringer:
+		s = x.String()
+	case nil:
+		return `""`
+	case bool, int, int64, int32, uint, uint64, uint32, float64, float32:
+		return fmt.Sprint(x)
+	default:
+		data, err := json.Marshal(x)
+		if err != nil {
+			s = fmt.Sprint(x)
+		} else {
+			s = string(data)
+		}
+	}
+	if s == "" || needsQuoting(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func needsQuoting(s string) bool {
+	if !utf8.ValidString(s) {
+		return true
+	}
+	for _, r := range s {
+		if r == ' ' || r == '=' || r == '"' || r == '\\' || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
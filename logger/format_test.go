@@ -0,0 +1,183 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// capture sends the log output to a file in format for the duration of the
+// test, and returns a function reading the lines written so far.
+func capture(t *testing.T, format Format) func() []string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.log")
+	prev := GetFormat()
+	if err := SetFormat(string(format)); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetFile(path, RotateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = CloseFile()
+		_ = SetFormat(string(prev))
+	})
+	return func() []string {
+		t.Helper()
+		Flush()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+}
+
+// awkward are messages and values that could break a line-based format.
+var awkward = []struct {
+	name  string
+	value string
+}{
+	{"newline", "first\nsecond"},
+	{"carriage return", "a\r\nb"},
+	{"invalid utf-8", "bad \xff\xfe bytes"},
+	{"quotes and equals", `k="v" x=y`},
+	{"control characters", "bell\a tab\t nul\x00"},
+	{"empty", ""},
+}
+
+func TestJSONLinesStayValid(t *testing.T) {
+	read := capture(t, FormatJSON)
+	for _, tt := range awkward {
+		LogKV("error", tt.value, "value", tt.value, tt.value, "as key")
+	}
+	lines := read()
+	if len(lines) != len(awkward) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(awkward), strings.Join(lines, "\n"))
+	}
+	for i, tt := range awkward {
+		t.Run(tt.name, func(t *testing.T) {
+			var m map[string]any
+			if err := json.Unmarshal([]byte(lines[i]), &m); err != nil {
+				t.Fatalf("invalid JSON %q: %v", lines[i], err)
+			}
+			// encoding/json replaces each invalid byte with U+FFFD.
+			want := string([]rune(tt.value))
+			if m[FieldMessage] != want || m["value"] != want {
+				t.Errorf("msg = %q, value = %q, want %q", m[FieldMessage], m["value"], want)
+			}
+			if m[FieldLevel] != "error" {
+				t.Errorf("level = %v, want error", m[FieldLevel])
+			}
+		})
+	}
+}
+
+func TestLogfmtLinesStayParseable(t *testing.T) {
+	read := capture(t, FormatLogfmt)
+	for _, tt := range awkward {
+		LogKV("error", tt.value, "value", tt.value, tt.value, "as key")
+	}
+	lines := read()
+	if len(lines) != len(awkward) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(awkward), strings.Join(lines, "\n"))
+	}
+	for i, tt := range awkward {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs, err := parseLogfmt(lines[i])
+			if err != nil {
+				t.Fatalf("unparseable line %q: %v", lines[i], err)
+			}
+			if pairs[FieldMessage] != tt.value || pairs["value"] != tt.value {
+				t.Errorf("msg = %q, value = %q, want %q", pairs[FieldMessage], pairs["value"], tt.value)
+			}
+			if pairs[logfmtKey(tt.value)] != "as key" {
+				t.Errorf("key %q missing from %q", logfmtKey(tt.value), lines[i])
+			}
+		})
+	}
+}
+
+func TestLogfmtKey(t *testing.T) {
+	tests := []struct{ key, want string }{
+		{"job", "job"},
+		{"http.status", "http.status"},
+		{"", "_"},
+		{"user name", "user_name"},
+		{"a=b", "a_b"},
+		{`say "hi"`, "say__hi_"},
+		{"line\nbreak", "line_break"},
+		{"bad\xffbyte", "bad_byte"},
+		{"ação", "ação"},
+	}
+	for _, tt := range tests {
+		if got := logfmtKey(tt.key); got != tt.want {
+			t.Errorf("logfmtKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+// parseLogfmt splits a logfmt line into its pairs. Keys must be bare and
+// valid UTF-8; values are bare or Go-quoted.
+func parseLogfmt(line string) (map[string]string, error) {
+	pairs := map[string]string{}
+	for line != "" {
+		key, rest, ok := strings.Cut(line, "=")
+		if !ok || key == "" || strings.ContainsAny(key, " \"") || !utf8.ValidString(key) {
+			return nil, fmt.Errorf("bad key in %q", line)
+		}
+		value := rest
+		if strings.HasPrefix(rest, `"`) {
+			end := 1
+			for ; end < len(rest) && rest[end] != '"'; end++ {
+				if rest[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(rest) {
+				return nil, fmt.Errorf("unterminated value in %q", line)
+			}
+			var err error
+			if value, err = strconv.Unquote(rest[:end+1]); err != nil {
+				return nil, err
+			}
+			rest = rest[end+1:]
+		} else {
+			value, rest, _ = strings.Cut(rest, " ")
+			rest = " " + rest
+		}
+		pairs[key] = value
+		line = strings.TrimPrefix(rest, " ")
+	}
+	return pairs, nil
+}
+
+func TestTextShowsFields(t *testing.T) {
+	read := capture(t, FormatText)
+	LogKV("error", "Job failed", "job", "backup", "error", "disk full")
+	With("task_id", "t1").Log("error", "Task failed")
+	Log("error", "No fields")
+
+	tests := []struct {
+		line int
+		want string
+	}{
+		{0, `Job failed error="disk full" job=backup`},
+		{1, "Task failed task_id=t1"},
+		{2, "No fields"},
+	}
+	lines := read()
+	if len(lines) != len(tests) {
+		t.Fatalf("got %d lines, want %d: %q", len(lines), len(tests), lines)
+	}
+	for _, tt := range tests {
+		if !strings.HasSuffix(lines[tt.line], tt.want) {
+			t.Errorf("line %d = %q, want it to end with %q", tt.line, lines[tt.line], tt.want)
+		}
+	}
+}
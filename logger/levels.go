@@ -0,0 +1,181 @@
+package logger
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FieldModule is the field naming the module a line comes from. Lines
+// carrying it are filtered by the level of that module, when one is set.
+const FieldModule = "module"
+
+var (
+	moduleMu     sync.RWMutex
+	moduleLevels map[string]LogLevel
+)
+
+// ParseLevelSpec parses a level spec such as "info,db=debug,http=warn": an
+// optional global level followed by module=level overrides. It returns the
+// global level ("" when absent) and the module levels.
+func ParseLevelSpec(spec string) (string, map[string]LogLevel, error) {
+	global := ""
+	modules := map[string]LogLevel{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, level, isModule := strings.Cut(part, "=")
+		if !isModule {
+			if _, ok := ParseLogLevel(part); !ok {
+				return "", nil, fmt.Errorf("unknown log level %q", part)
+			}
+			if global != "" {
+				return "", nil, fmt.Errorf("more than one global log level in %q", spec)
+			}
+			global = strings.ToLower(part)
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return "", nil, fmt.Errorf("missing module name in %q", part)
+		}
+		lvl, ok := ParseLogLevel(level)
+		if !ok {
+			return "", nil, fmt.Errorf("unknown log level %q for module %s", level, name)
+		}
+		modules[name] = lvl
+	}
+	return global, modules, nil
+}
+
+// SetLevelSpec applies a level spec as parsed by ParseLevelSpec. The module
+// levels replace the previous ones; the global level is left unchanged when
+// the spec has none.
+func SetLevelSpec(spec string) error {
+	global, modules, err := ParseLevelSpec(spec)
+	if err != nil {
+		return err
+	}
+	if global != "" {
+		setLogLevel(global)
+	}
+	moduleMu.Lock()
+	moduleLevels = modules
+	moduleMu.Unlock()
+	// A module may be below the global level, so logz must not filter.
+	g.SetLevel(logzLevel)
+	return nil
+}
+
+// SetModuleLevel sets the level of one module. An empty level removes the
+// override, so the module follows the global level again.
+func SetModuleLevel(module, level string) error {
+	module = strings.ToLower(module)
+	moduleMu.Lock()
+	defer moduleMu.Unlock()
+	if level == "" {
+		delete(moduleLevels, module)
+		return nil
+	}
+	lvl, ok := ParseLogLevel(level)
+	if !ok {
+		return fmt.Errorf("unknown log level %q", level)
+	}
+	next := make(map[string]LogLevel, len(moduleLevels)+1)
+	for k, v := range moduleLevels {
+		next[k] = v
+	}
+	next[module] = lvl
+	moduleLevels = next
+	g.SetLevel(logzLevel)
+	return nil
+}
+
+// LevelSpec returns the current levels in the form read by SetLevelSpec.
+func LevelSpec() string {
+	parts := []string{levelName(g.gLogLevel)}
+	moduleMu.RLock()
+	defer moduleMu.RUnlock()
+	names := make([]string, 0, len(moduleLevels))
+	for name := range moduleLevels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, name+"="+levelName(moduleLevels[name]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// ForModule returns a Scope whose lines carry the module field, and so are
+// filtered by the level of that module.
+func ForModule(name string) *Scope {
+	return With(FieldModule, strings.ToLower(name))
+}
+
+// levelFor returns the threshold of module: its own level when set, else
+// the global one.
+func levelFor(module string) LogLevel {
+	if module != "" {
+		moduleMu.RLock()
+		lvl, ok := moduleLevels[strings.ToLower(module)]
+		moduleMu.RUnlock()
+		if ok {
+			return lvl
+		}
+	}
+	return g.gLogLevel
+}
+
+// minLevel returns the lowest threshold of the global and module levels.
+func minLevel() LogLevel {
+	lvl := g.gLogLevel
+	moduleMu.RLock()
+	defer moduleMu.RUnlock()
+	for _, l := range moduleLevels {
+		if l < lvl {
+			lvl = l
+		}
+	}
+	return lvl
+}
+
+// moduleOf returns the module of a line from its fields.
+func moduleOf(fields map[string]any) string {
+	module, _ := fields[FieldModule].(string)
+	return module
+}
+
+// moduleName names the module of obj for GetLogger: the Module() of goforge
+// modules, else its lowercased type name.
+func moduleName[T any](obj *T) string {
+	if m, ok := any(obj).(interface{ Module() string }); ok {
+		return strings.ToLower(m.Module())
+	}
+	return strings.ToLower(reflect.TypeFor[T]().Name())
+}
+
+func levelName(lvl LogLevel) string {
+	switch lvl {
+	case LogLevelDebug:
+		return "debug"
+	case LogLevelNotice:
+		return "notice"
+	case LogLevelInfo:
+		return "info"
+	case LogLevelSuccess:
+		return "success"
+	case LogLevelWarn:
+		return "warn"
+	case LogLevelFatal:
+		return "fatal"
+	case LogLevelPanic:
+		return "panic"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,201 @@
+package logger
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLevelSpec(t *testing.T) {
+	tests := []struct {
+		spec        string
+		wantGlobal  string
+		wantModules map[string]LogLevel
+		wantErr     string
+	}{
+		{spec: "", wantModules: map[string]LogLevel{}},
+		{spec: "info", wantGlobal: "info", wantModules: map[string]LogLevel{}},
+		{spec: "WARN", wantGlobal: "warn", wantModules: map[string]LogLevel{}},
+		{
+			spec:        "info,db=debug,http=warn",
+			wantGlobal:  "info",
+			wantModules: map[string]LogLevel{"db": LogLevelDebug, "http": LogLevelWarn},
+		},
+		{
+			spec:        " DB = Debug , , error ",
+			wantGlobal:  "error",
+			wantModules: map[string]LogLevel{"db": LogLevelDebug},
+		},
+		{spec: "db=info,db=error", wantModules: map[string]LogLevel{"db": LogLevelError}},
+		{spec: "loud", wantErr: `unknown log level "loud"`},
+		{spec: "info,warn", wantErr: "more than one global log level"},
+		{spec: "=debug", wantErr: "missing module name"},
+		{spec: "db=chatty", wantErr: `unknown log level "chatty" for module db`},
+		{spec: "db=", wantErr: `unknown log level "" for module db`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			global, modules, err := ParseLevelSpec(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseLevelSpec(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLevelSpec(%q) error = %v", tt.spec, err)
+			}
+			if global != tt.wantGlobal || !reflect.DeepEqual(modules, tt.wantModules) {
+				t.Errorf("ParseLevelSpec(%q) = %q, %v, want %q, %v", tt.spec, global, modules, tt.wantGlobal, tt.wantModules)
+			}
+		})
+	}
+}
+
+// keepLevels restores the levels in place before the test.
+func keepLevels(t *testing.T) {
+	t.Helper()
+	saved := LevelSpec()
+	t.Cleanup(func() {
+		if err := SetLevelSpec(saved); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestSetLevelSpec(t *testing.T) {
+	keepLevels(t)
+	if err := SetLevelSpec("warn,http=error,db=debug"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := LevelSpec(), "warn,db=debug,http=error"; got != want {
+		t.Errorf("LevelSpec() = %q, want %q", got, want)
+	}
+
+	// A spec without a global level keeps the current one and replaces the
+	// module levels.
+	if err := SetLevelSpec("cache=info"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := LevelSpec(), "warn,cache=info"; got != want {
+		t.Errorf("LevelSpec() = %q, want %q", got, want)
+	}
+
+	if err := SetModuleLevel("Queue", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetModuleLevel("cache", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetModuleLevel("x", "chatty"); err == nil {
+		t.Error("SetModuleLevel accepted an unknown level")
+	}
+	if got, want := LevelSpec(), "warn,queue=debug"; got != want {
+		t.Errorf("LevelSpec() = %q, want %q", got, want)
+	}
+	if err := SetLevelSpec("info,bad=chatty"); err == nil {
+		t.Error("SetLevelSpec accepted an invalid spec")
+	}
+	if got, want := LevelSpec(), "warn,queue=debug"; got != want {
+		t.Errorf("LevelSpec() after a rejected spec = %q, want %q", got, want)
+	}
+}
+
+func TestModuleFiltering(t *testing.T) {
+	keepLevels(t)
+	if err := SetLevelSpec("warn,db=debug,http=error"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		module  string
+		logType string
+		want    bool
+	}{
+		{"", "info", false},
+		{"", "warn", true},
+		{"db", "info", true},
+		{"DB", "info", true},
+		{"http", "warn", false},
+		{"http", "error", true},
+		{"other", "warn", true},
+		{"other", "info", false},
+	}
+	for _, tt := range tests {
+		if got := willPrintLogFor(tt.module, tt.logType); got != tt.want {
+			t.Errorf("willPrintLogFor(%q, %q) = %v, want %v", tt.module, tt.logType, got, tt.want)
+		}
+	}
+	if got := minLevel(); got != LogLevelDebug {
+		t.Errorf("minLevel() = %v, want the debug level of db", got)
+	}
+
+	read := capture(t, FormatLogfmt)
+	ForModule("db").Log("info", "db line")
+	ForModule("http").Log("warn", "http line")
+	Log("info", "global line")
+	lines := read()
+	if len(lines) != 1 || !strings.Contains(lines[0], `msg="db line"`) {
+		t.Errorf("lines = %q, want only the db line", lines)
+	}
+}
+
+func TestModuleLevelBelowGlobalReachesWriter(t *testing.T) {
+	tests := []struct {
+		name string
+		set  func() error
+	}{
+		{"spec without global level", func() error { return SetLevelSpec("db=debug") }},
+		{"spec with global level", func() error { return SetLevelSpec("error,db=debug") }},
+		{"module level", func() error { return SetModuleLevel("db", "debug") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keepLevels(t)
+			if err := SetLevelSpec("error"); err != nil {
+				t.Fatal(err)
+			}
+			// logz starts at its own INFO threshold until told otherwise.
+			g.SetLevel("INFO")
+			if err := tt.set(); err != nil {
+				t.Fatal(err)
+			}
+			read := capture(t, FormatLogfmt)
+			ForModule("db").Log("debug", "db debug line")
+			ForModule("db").Log("info", "db info line")
+			Log("info", "global info line")
+			Log("debug", "global debug line")
+			lines := read()
+			if len(lines) != 2 || !strings.Contains(lines[0], `msg="db debug line"`) || !strings.Contains(lines[1], `msg="db info line"`) {
+				t.Errorf("lines = %q, want the db debug and info lines only", lines)
+			}
+		})
+	}
+}
+
+func TestFilteredLineNotice(t *testing.T) {
+	tests := []struct {
+		spec       string
+		wantNotice bool
+	}{
+		{"debug,http=warn", true},
+		{"error,http=warn", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			keepLevels(t)
+			if err := SetLevelSpec(tt.spec); err != nil {
+				t.Fatal(err)
+			}
+			read := capture(t, FormatLogfmt)
+			ForModule("http").Log("info", "token=abc")
+			lines := read()
+			got := len(lines) == 1 && strings.Contains(lines[0], "message not printed due to log level")
+			if got != tt.wantNotice {
+				t.Fatalf("lines = %q, want notice %v", lines, tt.wantNotice)
+			}
+			if got && strings.Contains(lines[0], "abc") {
+				t.Errorf("notice leaks the filtered message: %s", lines[0])
+			}
+		})
+	}
+}
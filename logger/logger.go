@@ -10,6 +10,8 @@ import (
 	"time"
 
 	manifest "github.com/rafa-mori/goforge/info"
+	"github.com/rafa-mori/goforge/metrics"
+	"github.com/rafa-mori/goforge/paths"
 	l "github.com/rafa-mori/logz"
 )
 
@@ -23,16 +25,22 @@ type GLog[T any] interface {
 	SetShowTrace(bool)
 	ObjLog(*T, string, ...string)
 	Log(string, ...any)
+	Logf(string, string, ...any)
+	LogKV(string, string, ...any)
 }
 type gLog[T any] struct {
 	l.Logger
 	gLogLevel  LogLevel // Global log level
 	gShowTrace bool     // Flag to show trace in logs
 	gDebug     bool     // Flag to show debug messages
+	module     string   // Module whose level filters the lines, if any
 }
 type LogType string
 type LogLevel int
 
+// logMessages counts the messages emitted, by level.
+var logMessages = metrics.NewCounter(metrics.Name("log_messages_total"), "Log messages emitted, by level.", "level")
+
 var (
 	info      manifest.Manifest
 	debug     bool
@@ -81,6 +89,30 @@ const (
 	LogLevelPanic
 )
 
+// ParseLogLevel returns the LogLevel named by level (case-insensitive).
+func ParseLogLevel(level string) (LogLevel, bool) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return LogLevelDebug, true
+	case "notice":
+		return LogLevelNotice, true
+	case "info":
+		return LogLevelInfo, true
+	case "success":
+		return LogLevelSuccess, true
+	case "warn":
+		return LogLevelWarn, true
+	case "error":
+		return LogLevelError, true
+	case "fatal":
+		return LogLevelFatal, true
+	case "panic":
+		return LogLevelPanic, true
+	default:
+		return LogLevelError, false
+	}
+}
+
 func getEnvOrDefault[T string | int | bool](key string, defaultValue T) T {
 	value, exists := os.LookupEnv(key)
 	if !exists {
@@ -113,39 +145,35 @@ func init() {
 			g.gLogLevel = LogLevelError
 			g.gShowTrace = showTrace
 			g.gDebug = debug
+			g.SetLevel(logzLevel)
 		}
 	}
+	// Honor the format before the configuration is loaded, so that a log
+	// pipeline never sees the first lines in another format.
+	if f, ok := ParseFormat(os.Getenv(formatEnv())); ok && f != FormatText {
+		logFormat = f
+		applyOutput()
+	}
+}
+
+// formatEnv names the variable holding the log format, <BIN>_LOG_FORMAT,
+// with the prefix the configuration uses for its environment variables.
+func formatEnv() string {
+	prefix := strings.NewReplacer("-", "_", ".", "_").Replace(paths.AppName())
+	return strings.ToUpper(prefix) + "_LOG_FORMAT"
 }
 
+// logzLevel is the threshold of the logz core. Lines are filtered by
+// willPrintLogFor, which knows the module levels, so logz lets every level
+// through.
+const logzLevel = "DEBUG"
+
 func SetDebug(d bool) {
 	if g == nil || Logger == nil {
 		_ = GetLogger[l.Logger](nil)
 	}
 	g.gDebug = d
-	if d {
-		g.SetLevel("debug")
-	} else {
-		switch g.gLogLevel {
-		case LogLevelDebug:
-			g.SetLevel("debug")
-		case LogLevelInfo:
-			g.SetLevel("info")
-		case LogLevelWarn:
-			g.SetLevel("warn")
-		case LogLevelError:
-			g.SetLevel("error")
-		case LogLevelFatal:
-			g.SetLevel("fatal")
-		case LogLevelPanic:
-			g.SetLevel("panic")
-		case LogLevelNotice:
-			g.SetLevel("notice")
-		case LogLevelSuccess:
-			g.SetLevel("success")
-		default:
-			g.SetLevel("info")
-		}
-	}
+	g.SetLevel(logzLevel)
 }
 func setLogLevel(logLevel string) {
 	if g == nil || Logger == nil {
@@ -154,33 +182,24 @@ func setLogLevel(logLevel string) {
 	switch strings.ToLower(logLevel) {
 	case "debug":
 		g.gLogLevel = LogLevelDebug
-		g.SetLevel("debug")
 	case "info":
 		g.gLogLevel = LogLevelInfo
-		g.SetLevel("info")
 	case "warn":
 		g.gLogLevel = LogLevelWarn
-		g.SetLevel("warn")
 	case "error":
 		g.gLogLevel = LogLevelError
-		g.SetLevel("error")
 	case "fatal":
 		g.gLogLevel = LogLevelFatal
-		g.SetLevel("fatal")
 	case "panic":
 		g.gLogLevel = LogLevelPanic
-		g.SetLevel("panic")
 	case "notice":
 		g.gLogLevel = LogLevelNotice
-		g.SetLevel("notice")
 	case "success":
 		g.gLogLevel = LogLevelSuccess
-		g.SetLevel("success")
 	default:
-		logLevel = "error"
 		g.gLogLevel = LogLevelError
-		g.SetLevel(logLevel)
 	}
+	g.SetLevel(logzLevel)
 }
 func getShowTrace() bool {
 	if debug {
@@ -194,31 +213,34 @@ func getShowTrace() bool {
 	}
 }
 func willPrintLog(logType string) bool {
+	return willPrintLogFor("", logType)
+}
+
+// willPrintLogFor reports whether a line of logType from module passes the
+// level of that module, or the global level when module has none.
+func willPrintLogFor(module, logType string) bool {
 	if debug {
 		return true
-	} else {
-		lTypeInt := LogLevelError
-		switch strings.ToLower(logType) {
-		case "debug":
-			lTypeInt = 0
-		case "fatal":
-			lTypeInt = 0
-		case "panic":
-			lTypeInt = 0
-		case "info":
-			lTypeInt = LogLevelInfo
-		case "warn":
-			lTypeInt = LogLevelWarn
-		case "error":
-			lTypeInt = LogLevelError
-		case "notice":
-			lTypeInt = LogLevelNotice
-		case "success":
-			lTypeInt = LogLevelSuccess
-		default:
-			lTypeInt = LogLevelError
-		}
-		return lTypeInt >= g.gLogLevel
+	}
+	return logTypeLevel(logType) >= levelFor(module)
+}
+
+// logTypeLevel returns the level a log type is filtered at. Fatal and
+// panic lines rank with debug ones.
+func logTypeLevel(logType string) LogLevel {
+	switch strings.ToLower(logType) {
+	case "debug", "fatal", "panic":
+		return LogLevelDebug
+	case "info":
+		return LogLevelInfo
+	case "warn":
+		return LogLevelWarn
+	case "notice":
+		return LogLevelNotice
+	case "success":
+		return LogLevelSuccess
+	default:
+		return LogLevelError
 	}
 }
 func GetLogger[T any](obj *T) GLog[l.Logger] {
@@ -237,13 +259,13 @@ func GetLogger[T any](obj *T) GLog[l.Logger] {
 	var lgr l.Logger
 	if objValueLogger := reflect.ValueOf(obj).Elem().MethodByName("GetLogger"); !objValueLogger.IsValid() {
 		if objValueLogger = reflect.ValueOf(obj).Elem().FieldByName("Logger"); !objValueLogger.IsValid() {
-			g.ErrorCtx(fmt.Sprintf("log object (%s) does not have a logger field", reflect.TypeFor[T]()), map[string]any{
+			g.ErrorCtx(fmt.Sprintf("log object (%s) does not have a logger field", reflect.TypeFor[T]()), redactFields(map[string]any{
 				"context":  "Log",
 				"logType":  "error",
 				"object":   obj,
 				"msg":      "object does not have a logger field",
 				"showData": getShowTrace(),
-			})
+			}))
 			return g
 		} else {
 			lgrC := objValueLogger.Convert(reflect.TypeFor[l.Logger]())
@@ -258,13 +280,13 @@ func GetLogger[T any](obj *T) GLog[l.Logger] {
 		lgr = g
 	}
 	if lgr == nil {
-		g.ErrorCtx(fmt.Sprintf("log object (%s) does not have a logger field", reflect.TypeFor[T]()), map[string]any{
+		g.ErrorCtx(fmt.Sprintf("log object (%s) does not have a logger field", reflect.TypeFor[T]()), redactFields(map[string]any{
 			"context":  "Log",
 			"logType":  "error",
 			"object":   obj,
 			"msg":      "object does not have a logger field",
 			"showData": getShowTrace(),
-		})
+		}))
 		return Logger
 	}
 	return &gLog[l.Logger]{
@@ -272,6 +294,7 @@ func GetLogger[T any](obj *T) GLog[l.Logger] {
 		gLogLevel:  g.gLogLevel,
 		gShowTrace: g.gShowTrace,
 		gDebug:     g.gDebug,
+		module:     moduleName(obj),
 	}
 }
 func getCtxMessageMap(logType, funcName, file string, line int) map[string]any {
@@ -298,13 +321,13 @@ func getCtxMessageMap(logType, funcName, file string, line int) map[string]any {
 func LogObjLogger[T any](obj *T, logType string, messages ...string) {
 	lgr := GetLogger(obj)
 	if lgr == nil {
-		g.ErrorCtx(fmt.Sprintf("log object (%s) does not have a logger field", reflect.TypeFor[T]()), map[string]any{
+		g.ErrorCtx(fmt.Sprintf("log object (%s) does not have a logger field", reflect.TypeFor[T]()), redactFields(map[string]any{
 			"context":  "Log",
 			"logType":  logType,
 			"object":   obj,
 			"msg":      messages,
 			"showData": getShowTrace(),
-		})
+		}))
 		return
 	}
 	pc, file, line, ok := runtime.Caller(1)
@@ -317,48 +340,121 @@ func LogObjLogger[T any](obj *T, logType string, messages ...string) {
 	logType = strings.ToLower(logType)
 
 	ctxMessageMap := getCtxMessageMap(logType, funcName, file, line)
+	if gl, ok := lgr.(*gLog[l.Logger]); ok && gl.module != "" {
+		ctxMessageMap[FieldModule] = gl.module
+	}
 	if logType != "" {
 		if reflect.TypeOf(logType).ConvertibleTo(reflect.TypeFor[LogType]()) {
 			lType := LogType(logType)
 			logging(lgr.GetLogger(), lType, fullMessage, ctxMessageMap)
 		} else {
-			lgr.GetLogger().ErrorCtx(fmt.Sprintf("logType (%s) is not valid", logType), ctxMessageMap)
+			lgr.GetLogger().ErrorCtx(fmt.Sprintf("logType (%s) is not valid", logType), redactFields(ctxMessageMap))
 		}
 	} else {
 		lgr.GetLogger().InfoCtx(fullMessage, ctxMessageMap)
 	}
 }
+
+// Log logs messages joined by spaces, like fmt.Sprintln without the newline.
 func Log(logType string, messages ...any) {
-	pc, file, line, ok := runtime.Caller(1)
+	logAt(2, logType, joinMessages(messages), nil)
+}
+
+// Logf logs a message formatted with fmt.Sprintf.
+func Logf(logType, format string, args ...any) {
+	logAt(2, logType, fmt.Sprintf(format, args...), nil)
+}
+
+// LogKV logs msg with fields given as alternating keys and values, as in
+// LogKV("info", "Job finished", "job", name, "duration", elapsed). The
+// fields land in the entry metadata instead of the message. A key without
+// a value, or a value in a key position, is logged under "!BADKEY".
+func LogKV(logType, msg string, keyvals ...any) {
+	logAt(2, logType, msg, kvFields(keyvals))
+}
+
+func joinMessages(messages []any) string {
+	switch len(messages) {
+	case 0:
+		return ""
+	case 1:
+		if s, ok := messages[0].(string); ok {
+			return s
+		}
+	}
+	return strings.TrimSuffix(fmt.Sprintln(messages...), "\n")
+}
+
+// kvFields turns alternating keys and values into a field map.
+func kvFields(keyvals []any) map[string]any {
+	if len(keyvals) == 0 {
+		return nil
+	}
+	fields := make(map[string]any, (len(keyvals)+1)/2)
+	for len(keyvals) > 0 {
+		key, ok := keyvals[0].(string)
+		switch {
+		case !ok:
+			setField(fields, "!BADKEY", keyvals[0])
+			keyvals = keyvals[1:]
+		case len(keyvals) == 1:
+			setField(fields, "!BADKEY", key)
+			keyvals = keyvals[1:]
+		default:
+			setField(fields, key, keyvals[1])
+			keyvals = keyvals[2:]
+		}
+	}
+	return fields
+}
+
+// setField stores a caller field in m. Keys used by the logger itself get
+// the "extra." prefix of the JSON format.
+func setField(m map[string]any, key string, value any) {
+	if _, reserved := metadataFields[key]; reserved {
+		key = "extra." + key
+	}
+	m[key] = value
+}
+
+// logAt logs message with fields, attributing it to the caller skip frames
+// up the stack.
+func logAt(skip int, logType, message string, fields map[string]any) {
+	pc, file, line, ok := runtime.Caller(skip)
 	if !ok {
 		g.ErrorCtx("Log: unable to get caller information", nil)
 		return
 	}
 	funcName := runtime.FuncForPC(pc).Name()
-	fullMessage := ""
-	if len(messages) > 0 {
-		fullMessage = fmt.Sprintf("%v", messages[0:])
-	}
 	logType = strings.ToLower(logType)
 	ctxMessageMap := getCtxMessageMap(logType, funcName, file, line)
+	for k, v := range fields {
+		ctxMessageMap[k] = v
+	}
 	if logType != "" {
 		if reflect.TypeOf(logType).ConvertibleTo(reflect.TypeFor[LogType]()) {
 			lType := LogType(logType)
 			ctxMessageMap["logType"] = logType
-			logging(g.Logger, lType, fullMessage, ctxMessageMap)
+			logging(g.Logger, lType, message, ctxMessageMap)
 		} else {
-			g.ErrorCtx(fmt.Sprintf("logType (%s) is not valid", logType), ctxMessageMap)
+			g.ErrorCtx(fmt.Sprintf("logType (%s) is not valid", logType), redactFields(ctxMessageMap))
 		}
 	} else {
-		logging(g.Logger, LogTypeInfo, fullMessage, ctxMessageMap)
+		logging(g.Logger, LogTypeInfo, message, ctxMessageMap)
 	}
 }
 func logging(lgr l.Logger, lType LogType, fullMessage string, ctxMessageMap map[string]any) {
 	lt := strings.ToLower(string(lType))
+	if lType == LogTypeFatal || lType == LogTypePanic {
+		// The process is about to die: write out the async buffer.
+		defer Flush()
+	}
 	if _, exist := ctxMessageMap["showData"]; !exist {
 		ctxMessageMap["showData"] = getShowTrace()
 	}
-	if willPrintLog(lt) {
+	if willPrintLogFor(moduleOf(ctxMessageMap), lt) {
+		logMessages.Inc(lt)
+		fullMessage = activeRedactor.Load().redactEntry(fullMessage, ctxMessageMap)
 		switch lType {
 		case LogTypeInfo:
 			lgr.InfoCtx(fullMessage, ctxMessageMap)
@@ -372,28 +468,47 @@ func logging(lgr l.Logger, lType LogType, fullMessage string, ctxMessageMap map[
 			lgr.NoticeCtx(fullMessage, ctxMessageMap)
 		case LogTypeSuccess:
 			lgr.SuccessCtx(fullMessage, ctxMessageMap)
-		case LogTypeFatal:
-			lgr.FatalCtx(fullMessage, ctxMessageMap)
-		case LogTypePanic:
+		case LogTypeFatal, LogTypePanic:
 			lgr.FatalCtx(fullMessage, ctxMessageMap)
 		default:
 			lgr.InfoCtx(fullMessage, ctxMessageMap)
 		}
-	} else {
-		ctxMessageMap["msg"] = fullMessage
+	} else if willPrintLog("debug") {
+		// The notice is itself a debug line, filtered at the global level.
+		ctxMessageMap["msg"] = activeRedactor.Load().redactEntry(fullMessage, ctxMessageMap)
 		ctxMessageMap["showData"] = false
 		lgr.DebugCtx("Log: message not printed due to log level", ctxMessageMap)
 	}
 }
 
-func (g *gLog[T]) GetLogger() l.Logger                 { return g.Logger }
-func (g *gLog[T]) GetLogLevel() LogLevel               { return g.gLogLevel }
-func (g *gLog[T]) GetShowTrace() bool                  { return g.gShowTrace }
-func (g *gLog[T]) GetDebug() bool                      { return g.gDebug }
-func (g *gLog[T]) SetLogLevel(logLevel string)         { setLogLevel(logLevel) }
-func (g *gLog[T]) SetShowTrace(showTrace bool)         { g.gShowTrace = showTrace }
-func (g *gLog[T]) SetDebug(d bool)                     { SetDebug(d); g.gDebug = d }
-func (g *gLog[T]) Log(logType string, messages ...any) { Log(logType, messages...) }
+func (g *gLog[T]) GetLogger() l.Logger         { return g.Logger }
+func (g *gLog[T]) GetLogLevel() LogLevel       { return g.gLogLevel }
+func (g *gLog[T]) GetShowTrace() bool          { return g.gShowTrace }
+func (g *gLog[T]) GetDebug() bool              { return g.gDebug }
+func (g *gLog[T]) SetLogLevel(logLevel string) { setLogLevel(logLevel) }
+func (g *gLog[T]) SetShowTrace(showTrace bool) { g.gShowTrace = showTrace }
+func (g *gLog[T]) SetDebug(d bool)             { SetDebug(d); g.gDebug = d }
+func (g *gLog[T]) Log(logType string, messages ...any) {
+	logAt(2, logType, joinMessages(messages), g.fields(nil))
+}
+func (g *gLog[T]) Logf(logType, format string, args ...any) {
+	logAt(2, logType, fmt.Sprintf(format, args...), g.fields(nil))
+}
+func (g *gLog[T]) LogKV(logType, msg string, keyvals ...any) {
+	logAt(2, logType, msg, g.fields(kvFields(keyvals)))
+}
+
+// fields adds the module of g to fields.
+func (g *gLog[T]) fields(fields map[string]any) map[string]any {
+	if g.module == "" {
+		return fields
+	}
+	if fields == nil {
+		fields = make(map[string]any, 1)
+	}
+	fields[FieldModule] = g.module
+	return fields
+}
 func (g *gLog[T]) ObjLog(obj *T, logType string, messages ...string) {
 	LogObjLogger(obj, logType, messages...)
 }
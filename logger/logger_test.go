@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJoinMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []any
+		want     string
+	}{
+		{"none", nil, ""},
+		{"string", []any{"plain [text]"}, "plain [text]"},
+		{"non-string", []any{42}, "42"},
+		{"several", []any{"a", 1, "b"}, "a 1 b"},
+		{"error", []any{"failed:", errString("boom")}, "failed: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := joinMessages(tt.messages); got != tt.want {
+				t.Errorf("joinMessages(%v) = %q, want %q", tt.messages, got, tt.want)
+			}
+		})
+	}
+}
+
+type errString string
+
+func (e errString) Error() string { return string(e) }
+
+func TestKVFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyvals []any
+		want    map[string]any
+	}{
+		{"none", nil, nil},
+		{"pairs", []any{"a", 1, "b", "two"}, map[string]any{"a": 1, "b": "two"}},
+		{"odd", []any{"a", 1, "b"}, map[string]any{"a": 1, "!BADKEY": "b"}},
+		{"non-string key", []any{42, "k", "v"}, map[string]any{"!BADKEY": 42, "k": "v"}},
+		{"reserved key", []any{"file", "x", "showData", true}, map[string]any{"extra.file": "x", "extra.showData": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := kvFields(tt.keyvals); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("kvFields(%v) = %v, want %v", tt.keyvals, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogfAndLogKV(t *testing.T) {
+	keepLevels(t)
+	if err := SetLevelSpec("debug"); err != nil {
+		t.Fatal(err)
+	}
+	read := capture(t, FormatJSON)
+
+	Log("info", "joined", 2, "parts")
+	Logf("warn", "%d of %s", 3, "[four]")
+	LogKV("error", "with fields", "job", "sync", 7, "odd")
+
+	tests := []struct {
+		msg    string
+		level  string
+		fields map[string]any
+	}{
+		{"joined 2 parts", "info", nil},
+		{"3 of [four]", "warn", nil},
+		{"with fields", "error", map[string]any{"job": "sync", "!BADKEY": "odd"}},
+	}
+	lines := read()
+	if len(lines) != len(tests) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(tests), strings.Join(lines, "\n"))
+	}
+	for i, tt := range tests {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(lines[i]), &m); err != nil {
+			t.Fatalf("invalid JSON %q: %v", lines[i], err)
+		}
+		if m[FieldMessage] != tt.msg || m[FieldLevel] != tt.level {
+			t.Errorf("line %d = %v %q, want %s %q", i, m[FieldLevel], m[FieldMessage], tt.level, tt.msg)
+		}
+		for k, v := range tt.fields {
+			if m[k] != v {
+				t.Errorf("line %d: %s = %v, want %v", i, k, m[k], v)
+			}
+		}
+		// The line is attributed to the caller, not to the logger.
+		if fn, _ := m[FieldFunc].(string); !strings.HasSuffix(fn, ".TestLogfAndLogKV") {
+			t.Errorf("line %d: func = %q, want the test function", i, fn)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	l "github.com/rafa-mori/logz"
+	lz "github.com/rafa-mori/logz/logger"
+)
+
+// ansiEscape matches the color sequences of the text formatter, which are
+// kept out of files.
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// entryWriter formats logz entries as lines on an io.Writer. It implements
+// the writer interface accepted by the logz SetWriter.
+type entryWriter struct {
+	mu        sync.Mutex
+	out       io.Writer
+	formatter entryFormatter
+	plain     bool
+}
+
+// entryFormatter renders one entry, without the trailing newline.
+type entryFormatter interface {
+	Format(entry lz.LogzEntry) (string, error)
+}
+
+// textFormatter is the colored text format of logz, followed by the fields
+// given by LogKV and scopes as key=value pairs. logz only prints those in
+// its metadata dump, which is off unless tracing.
+type textFormatter struct{ l.TextFormatter }
+
+func (f *textFormatter) Format(e lz.LogzEntry) (string, error) {
+	line, err := f.TextFormatter.Format(e)
+	if err != nil || showsData(e) {
+		return line, err
+	}
+	var b strings.Builder
+	b.WriteString(line)
+	for _, fl := range entryFields(e) {
+		if isFixedField(fl.key) {
+			continue
+		}
+		b.WriteByte(' ')
+		b.WriteString(logfmtKey(fl.key))
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(fl.value))
+	}
+	return b.String(), nil
+}
+
+// showsData reports whether logz dumps the metadata of e itself, as it does
+// when showData is set, or is missing on debug lines.
+func showsData(e lz.LogzEntry) bool {
+	v, ok := e.GetMetadata()["showData"]
+	if !ok {
+		return e.GetLevel() == "DEBUG"
+	}
+	return v == true || v == "true"
+}
+
+func (w *entryWriter) Write(entry any) error {
+	e, ok := entry.(lz.LogzEntry)
+	if !ok {
+		return fmt.Errorf("unsupported log entry type: %T", entry)
+	}
+	line, err := w.formatter.Format(e)
+	if err != nil {
+		return err
+	}
+	if w.plain {
+		line = e.GetTimestamp().Format(time.RFC3339) + " " + ansiEscape.ReplaceAllString(line, "")
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err = io.WriteString(w.out, line+"\n")
+	return err
+}
+
+var (
+	outputMu  sync.Mutex
+	logFile   *RotatingFile
+	logFormat = FormatText
+	slogOut   slog.Handler
+	asyncOpts AsyncOptions
+	asyncOut  *asyncWriter
+)
+
+// SetFile sends the log output to the file at path, rotated according to
+// opts. An empty path sends the output back to stdout. Calling it again
+// with the same settings keeps the current file.
+func SetFile(path string, opts RotateOptions) error {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	if logFile != nil && logFile.Path() == path && logFile.Options() == opts {
+		return nil
+	}
+	var next *RotatingFile
+	if path != "" {
+		var err error
+		if next, err = OpenRotatingFile(path, opts); err != nil {
+			return err
+		}
+	}
+
+	prev := logFile
+	logFile = next
+	applyOutput()
+	if prev != nil {
+		_ = prev.Close()
+	}
+	return nil
+}
+
+// ReopenFile reopens the log file at the same path, so that external tools
+// such as logrotate can move it. It does nothing when logging to stdout.
+func ReopenFile() error {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	if logFile == nil {
+		return nil
+	}
+	return logFile.Reopen()
+}
+
+// SetFormat selects the format of log entries: "text" (the default), "json"
+// or "logfmt".
+func SetFormat(format string) error {
+	f, ok := ParseFormat(format)
+	if !ok {
+		return fmt.Errorf("unknown log format %q (want text, json or logfmt)", format)
+	}
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	if f != logFormat {
+		logFormat = f
+		applyOutput()
+	}
+	return nil
+}
+
+// GetFormat returns the current log format.
+func GetFormat() Format {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	return logFormat
+}
+
+// applyOutput installs the writer for the current slog handler, file,
+// format and async mode. outputMu must be held.
+func applyOutput() {
+	var w interface{ Write(entry any) error }
+	var out io.Writer = os.Stdout
+	if logFile != nil {
+		out = logFile
+	}
+	switch {
+	case slogOut != nil:
+		w = &slogWriter{h: slogOut}
+	case logFormat == FormatJSON:
+		w = &entryWriter{out: out, formatter: jsonFormatter{}}
+	case logFormat == FormatLogfmt:
+		w = &entryWriter{out: out, formatter: logfmtFormatter{}}
+	default:
+		w = &entryWriter{out: out, formatter: &textFormatter{}, plain: logFile != nil}
+	}
+
+	prev := asyncOut
+	asyncOut = nil
+	if asyncOpts.BufferSize > 0 {
+		asyncOut = newAsyncWriter(w, asyncOpts)
+		w = asyncOut
+	}
+	g.SetWriter(w)
+	if prev != nil {
+		prev.Close()
+	}
+}
+
+// LogFile returns the path of the log file, or "" when logging to stdout.
+func LogFile() string {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	if logFile == nil {
+		return ""
+	}
+	return logFile.Path()
+}
+
+// CloseFile flushes and closes the log file, sending the output back to
+// stdout. It is meant to be called on exit.
+func CloseFile() error {
+	return SetFile("", RotateOptions{})
+}
@@ -0,0 +1,323 @@
+package logger
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Redacted replaces the values hidden by redaction.
+const Redacted = "[REDACTED]"
+
+// DefaultRedactKeys are the key fragments whose values are redacted. A key
+// matches when it contains one of them, ignoring case, so "db_password"
+// and "X-Auth-Token" match too.
+var DefaultRedactKeys = []string{"password", "passwd", "secret", "token", "authorization", "api_key", "apikey", "private_key"}
+
+// DefaultRedactPatterns match common credential formats in strings.
+var DefaultRedactPatterns = []string{
+	`(?i)\bbearer\s+[a-z0-9\-._~+/]+=*`,
+	`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`,
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	`\bgh[pousr]_[A-Za-z0-9]{36,}\b`,
+	`\bgithub_pat_[A-Za-z0-9_]{22,}\b`,
+	`\bxox[abposr]-[A-Za-z0-9-]{10,}`,
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+}
+
+// maxRedactDepth bounds the walk of nested values.
+const maxRedactDepth = 10
+
+// RedactOptions configures redaction. The zero value redacts with the
+// defaults.
+type RedactOptions struct {
+	// Disabled turns redaction off.
+	Disabled bool
+	// Keys are key fragments redacted on top of DefaultRedactKeys.
+	Keys []string
+	// Patterns are regular expressions redacted on top of
+	// DefaultRedactPatterns.
+	Patterns []string
+}
+
+// redactor hides secrets in messages and fields before they reach the
+// output.
+type redactor struct {
+	keys     []string
+	patterns []*regexp.Regexp
+	// assign matches key=value and key: value pairs in messages whose key
+	// is sensitive, keeping the key.
+	assign *regexp.Regexp
+	// secretTypes caches whether a struct type has fields to redact.
+	secretTypes sync.Map
+}
+
+var activeRedactor atomic.Pointer[redactor]
+
+func init() {
+	r, _ := newRedactor(RedactOptions{})
+	activeRedactor.Store(r)
+}
+
+func newRedactor(opts RedactOptions) (*redactor, error) {
+	if opts.Disabled {
+		return nil, nil
+	}
+	r := &redactor{}
+	var quoted []string
+	for _, k := range append(append([]string{}, DefaultRedactKeys...), opts.Keys...) {
+		if k = normalizeKey(k); k != "" {
+			r.keys = append(r.keys, k)
+			quoted = append(quoted, regexp.QuoteMeta(k))
+		}
+	}
+	for _, p := range append(append([]string{}, DefaultRedactPatterns...), opts.Patterns...) {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	if len(quoted) > 0 {
+		r.assign = regexp.MustCompile(`(?i)(\b[\w-]*(?:` + strings.Join(quoted, "|") + `)[\w-]*"?\s*[=:]\s*)("[^"]*"|[^\s,;&]+)`)
+	}
+	return r, nil
+}
+
+// SetRedaction replaces the redaction settings. Redaction is on by default.
+func SetRedaction(opts RedactOptions) error {
+	r, err := newRedactor(opts)
+	if err != nil {
+		return err
+	}
+	activeRedactor.Store(r)
+	return nil
+}
+
+// ValidateRedaction reports whether opts would be accepted by SetRedaction.
+func ValidateRedaction(opts RedactOptions) error {
+	_, err := newRedactor(opts)
+	return err
+}
+
+// Redact returns s with the secrets it contains replaced by Redacted.
+func Redact(s string) string {
+	return activeRedactor.Load().redactString(s)
+}
+
+func normalizeKey(k string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(k)), "-", "_")
+}
+
+func (r *redactor) sensitiveKey(key string) bool {
+	key = normalizeKey(key)
+	for _, k := range r.keys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) redactString(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, Redacted)
+	}
+	if r.assign != nil {
+		s = r.assign.ReplaceAllString(s, "${1}"+Redacted)
+	}
+	return s
+}
+
+// redactEntry redacts the message and the fields of a line in place.
+// Fields set by the logger itself are left alone.
+func (r *redactor) redactEntry(msg string, fields map[string]any) string {
+	if r == nil {
+		return msg
+	}
+	for k, v := range fields {
+		if _, own := metadataFields[k]; own {
+			continue
+		}
+		fields[k] = r.redactValue(k, v, 0)
+	}
+	return r.redactString(msg)
+}
+
+// redactFields redacts fields in place with the active settings and
+// returns them.
+func redactFields(fields map[string]any) map[string]any {
+	activeRedactor.Load().redactEntry("", fields)
+	return fields
+}
+
+// redactValue returns v with its secrets redacted: values under a sensitive
+// key or a field tagged `redact:"true"` are replaced, strings, byte slices
+// and raw JSON are scrubbed, and structs, maps and slices are copied with
+// their contents redacted. Structs become maps keyed by their JSON field
+// names; those with fields to redact are walked even when they have their
+// own String or marshaling methods, which would print those fields.
+func (r *redactor) redactValue(key string, v any, depth int) any {
+	if v == nil {
+		return nil
+	}
+	if key != "" && r.sensitiveKey(key) {
+		return Redacted
+	}
+	switch x := v.(type) {
+	case string:
+		return r.redactString(x)
+	case json.RawMessage:
+		return r.redactJSON(x, depth)
+	case []byte:
+		if s := r.redactString(string(x)); s != string(x) {
+			return []byte(s)
+		}
+		return v
+	}
+	if depth < maxRedactDepth {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+			rv = rv.Elem()
+		}
+		if rv.Kind() == reflect.Struct && r.hasSecrets(rv.Type()) {
+			return r.redactStruct(rv, depth)
+		}
+	}
+	switch x := v.(type) {
+	case error:
+		if s := r.redactString(x.Error()); s != x.Error() {
+			return s
+		}
+		return v
+	case fmt.Stringer:
+		if s := r.redactString(x.String()); s != x.String() {
+			return s
+		}
+		return v
+	case json.Marshaler, encoding.TextMarshaler:
+		return v
+	}
+	if depth >= maxRedactDepth {
+		return v
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return v
+		}
+		return r.redactValue("", rv.Elem().Interface(), depth+1)
+	case reflect.Struct:
+		return r.redactStruct(rv, depth)
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return v
+		}
+		out := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			k := iter.Key().String()
+			out[k] = r.redactValue(k, iter.Value().Interface(), depth+1)
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return v
+		}
+		out := make([]any, rv.Len())
+		for i := range out {
+			out[i] = r.redactValue("", rv.Index(i).Interface(), depth+1)
+		}
+		return out
+	}
+	return v
+}
+
+// redactJSON redacts raw JSON as the value it decodes to, keeping it valid
+// JSON. Invalid JSON is scrubbed as a string.
+func (r *redactor) redactJSON(raw json.RawMessage, depth int) any {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var decoded any
+	if err := dec.Decode(&decoded); err != nil || dec.More() {
+		if s := r.redactString(string(raw)); s != string(raw) {
+			return s
+		}
+		return raw
+	}
+	redacted, err := json.Marshal(r.redactValue("", decoded, depth+1))
+	if err != nil {
+		return raw
+	}
+	if original, err := json.Marshal(decoded); err == nil && bytes.Equal(original, redacted) {
+		return raw
+	}
+	return json.RawMessage(redacted)
+}
+
+// hasSecrets reports whether the struct type t, or a struct it holds, has
+// a field tagged `redact:"true"` or named with a sensitive key.
+func (r *redactor) hasSecrets(t reflect.Type) bool {
+	if v, ok := r.secretTypes.Load(t); ok {
+		return v.(bool)
+	}
+	// Recursive types are seen as clean while being checked.
+	r.secretTypes.Store(t, false)
+	found := false
+	for i := 0; i < t.NumField() && !found; i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.Tag.Get("redact") == "true" || r.sensitiveKey(f.Name) || (name != "" && r.sensitiveKey(name)) {
+			found = true
+			break
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		found = ft.Kind() == reflect.Struct && r.hasSecrets(ft)
+	}
+	r.secretTypes.Store(t, found)
+	return found
+}
+
+func (r *redactor) redactStruct(rv reflect.Value, depth int) map[string]any {
+	t := rv.Type()
+	out := make(map[string]any, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		if f.Tag.Get("redact") == "true" || r.sensitiveKey(f.Name) {
+			out[name] = Redacted
+			continue
+		}
+		out[name] = r.redactValue(name, rv.Field(i).Interface(), depth+1)
+	}
+	return out
+}
@@ -0,0 +1,274 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat stamps rotated files, e.g. goforge.log.20261018-150405.
+const backupTimeFormat = "20060102-150405"
+
+// RotateOptions controls when a RotatingFile rotates and what it keeps.
+type RotateOptions struct {
+	// MaxSize rotates the file before a write would make it larger, in
+	// bytes. Zero disables size rotation.
+	MaxSize int64
+	// MaxAge rotates the file once it has been written to for that long.
+	// Zero disables age rotation.
+	MaxAge time.Duration
+	// MaxBackups is how many rotated files are kept. Zero keeps them all.
+	MaxBackups int
+	// Compress gzips rotated files.
+	Compress bool
+}
+
+// RotatingFile is an io.Writer appending to a file that is rotated on size
+// or age. Rotated files are renamed with a timestamp suffix, optionally
+// compressed, and pruned down to MaxBackups. It is safe for concurrent use.
+type RotatingFile struct {
+	path string
+	opts RotateOptions
+
+	mu       sync.Mutex
+	f        *os.File
+	size     int64
+	openedAt time.Time
+	bg       sync.WaitGroup
+	bgMu     sync.Mutex
+}
+
+// OpenRotatingFile opens path for appending, creating it and its directory
+// when missing.
+func OpenRotatingFile(path string, opts RotateOptions) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, opts: opts}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Path returns the path of the active file.
+func (r *RotatingFile) Path() string { return r.path }
+
+// Options returns the rotation options.
+func (r *RotatingFile) Options() RotateOptions { return r.opts }
+
+// open opens the active file. Its age counts from the last rotation, so
+// age rotation keeps working across restarts. r.mu must be held.
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	r.f, r.size, r.openedAt = f, st.Size(), time.Now()
+	if backups := r.backups(); st.Size() > 0 && len(backups) > 0 {
+		if bst, err := os.Stat(backups[len(backups)-1]); err == nil {
+			r.openedAt = bst.ModTime()
+		}
+	}
+	return nil
+}
+
+// Write appends p, rotating first when p would exceed MaxSize or the file
+// is older than MaxAge.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	if r.size > 0 && r.shouldRotate(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingFile) shouldRotate(next int64) bool {
+	if r.opts.MaxSize > 0 && r.size+next > r.opts.MaxSize {
+		return true
+	}
+	return r.opts.MaxAge > 0 && time.Since(r.openedAt) >= r.opts.MaxAge
+}
+
+// Rotate rotates the file now.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rotate()
+}
+
+// rotate renames the active file and opens a new one. Compression and
+// pruning run in the background. r.mu must be held.
+func (r *RotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	backup := r.path + "." + time.Now().Format(backupTimeFormat)
+	for i := 1; fileExists(backup) || fileExists(backup+".gz"); i++ {
+		backup = fmt.Sprintf("%s.%s-%d", r.path, time.Now().Format(backupTimeFormat), i)
+	}
+	if err := os.Rename(r.path, backup); err != nil {
+		return err
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	r.openedAt = time.Now()
+
+	r.bg.Add(1)
+	go func() {
+		defer r.bg.Done()
+		r.bgMu.Lock()
+		defer r.bgMu.Unlock()
+		if r.opts.Compress {
+			// A burst of rotations may have pruned backup already.
+			if err := compressFile(backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+				fmt.Fprintf(os.Stderr, "Failed to compress %s: %v\n", backup, err)
+			}
+		}
+		r.prune()
+	}()
+	return nil
+}
+
+// Reopen closes and reopens the file at the same path, for use after an
+// external tool such as logrotate moved it away.
+func (r *RotatingFile) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f != nil {
+		_ = r.f.Close()
+	}
+	return r.open()
+}
+
+// Close closes the file and waits for background compression.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.f != nil {
+		err = r.f.Close()
+		r.f = nil
+	}
+	r.mu.Unlock()
+	r.bg.Wait()
+	return err
+}
+
+// backupSuffix matches the suffix rotate gives the backups of a file:
+// .YYYYMMDD-HHMMSS, then an optional -n and .gz.
+var backupSuffix = regexp.MustCompile(`^\.\d{8}-\d{6}(?:-\d+)?(?:\.gz)?$`)
+
+// backups returns the rotated files, oldest first. Only names made by
+// rotate are listed, so pruning never touches unrelated files sharing the
+// prefix of the log file.
+func (r *RotatingFile) backups() []string {
+	dir, base := filepath.Split(r.path)
+	if dir == "" {
+		dir = "."
+	}
+	entries, _ := os.ReadDir(dir)
+	type backup struct {
+		path    string
+		modTime time.Time
+	}
+	list := make([]backup, 0, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || !strings.HasPrefix(name, base) || !backupSuffix.MatchString(name[len(base):]) {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			list = append(list, backup{filepath.Join(dir, name), info.ModTime()})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].modTime.Equal(list[j].modTime) {
+			return list[i].modTime.Before(list[j].modTime)
+		}
+		return list[i].path < list[j].path
+	})
+	paths := make([]string, len(list))
+	for i, b := range list {
+		paths[i] = b.path
+	}
+	return paths
+}
+
+// prune removes the oldest backups beyond MaxBackups.
+func (r *RotatingFile) prune() {
+	if r.opts.MaxBackups <= 0 {
+		return
+	}
+	backups := r.backups()
+	for len(backups) > r.opts.MaxBackups {
+		if err := os.Remove(backups[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Failed to remove old log file %s: %v\n", backups[0], err)
+		}
+		backups = backups[1:]
+	}
+}
+
+// compressFile gzips path into path.gz and removes path.
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	tmp := path + ".gz.tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		_ = out.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		return err
+	}
+	// Keep the modification time, which orders the backups for pruning.
+	if st, err := in.Stat(); err == nil {
+		_ = os.Chtimes(path+".gz", st.ModTime(), st.ModTime())
+	}
+	return os.Remove(path)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,193 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackupsIgnoreUnrelatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	files := []struct {
+		name   string
+		backup bool
+	}{
+		{"app.log", false},
+		{"app.log.20261018-150405", true},
+		{"app.log.20261018-150405-1", true},
+		{"app.log.20261018-150406.gz", true},
+		{"app.log.20261018-150406-2.gz", true},
+		{"app.log.bak", false},
+		{"app.log.20261018", false},
+		{"app.log.20261018-150405.gz.tmp", false},
+		{"app.log.20261018-150405.txt", false},
+		{"app.logx.20261018-150405", false},
+	}
+	base := time.Now().Add(-time.Hour)
+	for i, f := range files {
+		p := filepath.Join(dir, f.name)
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		mt := base.Add(time.Duration(i) * time.Second)
+		if err := os.Chtimes(p, mt, mt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := &RotatingFile{path: path, opts: RotateOptions{MaxBackups: 1}}
+	var want []string
+	for _, f := range files {
+		if f.backup {
+			want = append(want, filepath.Join(dir, f.name))
+		}
+	}
+	if got := r.backups(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("backups() = %v, want %v", got, want)
+	}
+
+	r.prune()
+	for _, f := range files {
+		_, err := os.Stat(filepath.Join(dir, f.name))
+		kept := err == nil
+		wantKept := !f.backup || f.name == "app.log.20261018-150406-2.gz"
+		if kept != wantKept {
+			t.Errorf("%s kept = %v, want %v", f.name, kept, wantKept)
+		}
+	}
+}
+
+func TestRotateKeepsMaxBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	r, err := OpenRotatingFile(path, RotateOptions{MaxSize: 10, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := r.Write([]byte("0123456789\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(r.backups()); got != 2 {
+		t.Errorf("%d backups left, want 2", got)
+	}
+}
+
+// readFile returns the content of path, gunzipping it when it ends in .gz.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	var rd io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rd = zr
+	}
+	b, err := io.ReadAll(rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRotate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts RotateOptions
+		// age backdates the file before the second write.
+		age        time.Duration
+		wantBackup bool
+	}{
+		{"under size", RotateOptions{MaxSize: 100}, 0, false},
+		{"over size", RotateOptions{MaxSize: 8}, 0, true},
+		{"over size compressed", RotateOptions{MaxSize: 8, Compress: true}, 0, true},
+		{"young", RotateOptions{MaxAge: time.Hour}, 0, false},
+		{"old", RotateOptions{MaxAge: time.Hour}, 2 * time.Hour, true},
+		{"old compressed", RotateOptions{MaxAge: time.Hour, Compress: true}, 2 * time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			r, err := OpenRotatingFile(path, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.Write([]byte("first\n")); err != nil {
+				t.Fatal(err)
+			}
+			r.mu.Lock()
+			r.openedAt = r.openedAt.Add(-tt.age)
+			r.mu.Unlock()
+			if _, err := r.Write([]byte("second\n")); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			backups := r.backups()
+			if !tt.wantBackup {
+				if len(backups) != 0 {
+					t.Fatalf("backups = %v, want none", backups)
+				}
+				if got := readFile(t, path); got != "first\nsecond\n" {
+					t.Errorf("active file = %q", got)
+				}
+				return
+			}
+			if len(backups) != 1 {
+				t.Fatalf("backups = %v, want one", backups)
+			}
+			if gz := strings.HasSuffix(backups[0], ".gz"); gz != tt.opts.Compress {
+				t.Errorf("backup %s compressed = %v, want %v", backups[0], gz, tt.opts.Compress)
+			}
+			if got := readFile(t, backups[0]); got != "first\n" {
+				t.Errorf("backup = %q, want %q", got, "first\n")
+			}
+			if got := readFile(t, path); got != "second\n" {
+				t.Errorf("active file = %q, want %q", got, "second\n")
+			}
+		})
+	}
+}
+
+func TestReopenAfterExternalRename(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	r, err := OpenRotatingFile(path, RotateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = r.Close() })
+	if _, err := r.Write([]byte("before\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Write([]byte("after\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path+".1"); got != "before\n" {
+		t.Errorf("moved file = %q, want %q", got, "before\n")
+	}
+	if got := readFile(t, path); got != "after\n" {
+		t.Errorf("reopened file = %q, want %q", got, "after\n")
+	}
+}
@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+)
+
+// Scope is a logger carrying fields that are added to every line it logs,
+// such as a request ID or a job name. A nil *Scope logs without fields.
+type Scope struct {
+	fields map[string]any
+}
+
+// With returns a Scope with fields given as alternating keys and values,
+// as in LogKV.
+func With(keyvals ...any) *Scope {
+	return (*Scope)(nil).With(keyvals...)
+}
+
+// With returns a child of s with more fields. Fields of the child replace
+// those of s with the same key.
+func (s *Scope) With(keyvals ...any) *Scope {
+	return &Scope{fields: s.merge(kvFields(keyvals))}
+}
+
+// Fields returns the fields of s. The map must not be modified.
+func (s *Scope) Fields() map[string]any {
+	if s == nil {
+		return nil
+	}
+	return s.fields
+}
+
+// Log logs messages joined by spaces with the fields of s.
+func (s *Scope) Log(logType string, messages ...any) {
+	logAt(2, logType, joinMessages(messages), s.Fields())
+}
+
+// Logf logs a message formatted with fmt.Sprintf with the fields of s.
+func (s *Scope) Logf(logType, format string, args ...any) {
+	logAt(2, logType, fmt.Sprintf(format, args...), s.Fields())
+}
+
+// LogKV logs msg with the fields of s and keyvals.
+func (s *Scope) LogKV(logType, msg string, keyvals ...any) {
+	logAt(2, logType, msg, s.merge(kvFields(keyvals)))
+}
+
+// merge returns the fields of s overridden by fields, without modifying
+// either map.
+func (s *Scope) merge(fields map[string]any) map[string]any {
+	if len(fields) == 0 {
+		return s.Fields()
+	}
+	merged := make(map[string]any, len(s.Fields())+len(fields))
+	for k, v := range s.Fields() {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return merged
+}
+
+type scopeKey struct{}
+
+// IntoContext returns a copy of ctx carrying s.
+func IntoContext(ctx context.Context, s *Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, s)
+}
+
+// FromContext returns the Scope carried by ctx, or a Scope without fields.
+// It never returns nil.
+func FromContext(ctx context.Context) *Scope {
+	if ctx != nil {
+		if s, ok := ctx.Value(scopeKey{}).(*Scope); ok && s != nil {
+			return s
+		}
+	}
+	return &Scope{}
+}
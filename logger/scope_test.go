@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScopeWith(t *testing.T) {
+	parent := With("a", 1)
+	child := parent.With("b", 2)
+	sibling := parent.With("a", 3)
+	tests := []struct {
+		name  string
+		scope *Scope
+		want  map[string]any
+	}{
+		{"nil", nil, nil},
+		{"parent", parent, map[string]any{"a": 1}},
+		{"child", child, map[string]any{"a": 1, "b": 2}},
+		{"sibling", sibling, map[string]any{"a": 3}},
+		{"module", ForModule("DB").With("a", 1), map[string]any{FieldModule: "db", "a": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.scope.Fields(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Fields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeContext(t *testing.T) {
+	s := With("request_id", "r1")
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want map[string]any
+	}{
+		{"none", context.Background(), nil},
+		{"nil scope", IntoContext(context.Background(), nil), nil},
+		{"carried", IntoContext(context.Background(), s), map[string]any{"request_id": "r1"}},
+		{"derived", context.WithValue(IntoContext(context.Background(), s), struct{}{}, 1), map[string]any{"request_id": "r1"}},
+		{"replaced", IntoContext(IntoContext(context.Background(), s), s.With("job", "j")), map[string]any{"request_id": "r1", "job": "j"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FromContext(tt.ctx)
+			if got == nil {
+				t.Fatal("FromContext() = nil")
+			}
+			if len(got.Fields()) != len(tt.want) || (len(tt.want) > 0 && !reflect.DeepEqual(got.Fields(), tt.want)) {
+				t.Errorf("FromContext().Fields() = %v, want %v", got.Fields(), tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeOutput(t *testing.T) {
+	keepLevels(t)
+	if err := SetLevelSpec("warn,db=debug"); err != nil {
+		t.Fatal(err)
+	}
+	read := capture(t, FormatJSON)
+
+	s := ForModule("db").With("request_id", "r1")
+	s.Log("debug", "kept by the module level")
+	s.LogKV("info", "with more fields", "rows", 2)
+	With("request_id", "r2").Log("info", "filtered by the global level")
+	s.Logf("warn", "after %s", "LogKV")
+
+	lines := read()
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	for i, line := range lines {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		if m[FieldModule] != "db" || m["request_id"] != "r1" {
+			t.Errorf("line %d lost the scope fields: %s", i, line)
+		}
+		// Fields given to LogKV do not stick to the scope.
+		if _, ok := m["rows"]; ok != (i == 1) {
+			t.Errorf("line %d: rows present = %v: %s", i, ok, line)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+
+	lz "github.com/rafa-mori/logz/logger"
+)
+
+// slog levels of the log types without a slog counterpart. They keep the
+// order of the LogLevel constants: notice sits between debug and info, and
+// success between info and warn.
+const (
+	SlogLevelNotice  = slog.LevelInfo - 2
+	SlogLevelSuccess = slog.LevelInfo + 2
+	SlogLevelFatal   = slog.LevelError + 4
+)
+
+// SlogLevel returns the slog level of a log type. Unknown types map to
+// slog.LevelInfo.
+func SlogLevel(logType string) slog.Level {
+	switch LogType(strings.ToLower(logType)) {
+	case LogTypeDebug:
+		return slog.LevelDebug
+	case LogTypeNotice:
+		return SlogLevelNotice
+	case LogTypeSuccess:
+		return SlogLevelSuccess
+	case LogTypeWarn:
+		return slog.LevelWarn
+	case LogTypeError:
+		return slog.LevelError
+	case LogTypeFatal, LogTypePanic:
+		return SlogLevelFatal
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// LogTypeOf returns the log type of a slog level, rounding down to the
+// closest type. Levels above slog.LevelError map to error: slog callers do
+// not expect the process to exit, so they never reach fatal.
+func LogTypeOf(level slog.Level) LogType {
+	switch {
+	case level < SlogLevelNotice:
+		return LogTypeDebug
+	case level < slog.LevelInfo:
+		return LogTypeNotice
+	case level < SlogLevelSuccess:
+		return LogTypeInfo
+	case level < slog.LevelWarn:
+		return LogTypeSuccess
+	case level < slog.LevelError:
+		return LogTypeWarn
+	default:
+		return LogTypeError
+	}
+}
+
+// SlogHandler is a slog.Handler writing through the global logger, with its
+// level filtering and output settings. Route the standard library and
+// third-party packages through it with:
+//
+//	slog.SetDefault(slog.New(logger.NewSlogHandler()))
+type SlogHandler struct {
+	attrs  map[string]any
+	prefix string
+}
+
+// NewSlogHandler returns a handler writing through the global logger.
+func NewSlogHandler() *SlogHandler {
+	return &SlogHandler{}
+}
+
+// Enabled reports whether the global logger prints messages of level. A
+// handler without a module attribute accepts the levels of every module,
+// since a record may still name one; Handle then filters it.
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	if module := moduleOf(h.attrs); module != "" || debug {
+		return willPrintLogFor(module, string(LogTypeOf(level)))
+	}
+	return logTypeLevel(string(LogTypeOf(level))) >= minLevel()
+}
+
+// Handle logs r. Its attributes become entry fields, qualified by the
+// enclosing groups.
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error {
+	funcName, file, line := "", "", 0
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		funcName, file, line = frame.Function, frame.File, frame.Line
+	}
+	lType := LogTypeOf(r.Level)
+	ctxMessageMap := getCtxMessageMap(string(lType), funcName, file, line)
+	for k, v := range h.attrs {
+		ctxMessageMap[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addSlogAttr(ctxMessageMap, h.prefix, a)
+		return true
+	})
+	logging(g.Logger, lType, r.Message, ctxMessageMap)
+	return nil
+}
+
+// WithAttrs returns a handler adding attrs to every record.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	next := &SlogHandler{attrs: make(map[string]any, len(h.attrs)+len(attrs)), prefix: h.prefix}
+	for k, v := range h.attrs {
+		next.attrs[k] = v
+	}
+	for _, a := range attrs {
+		addSlogAttr(next.attrs, h.prefix, a)
+	}
+	return next
+}
+
+// WithGroup returns a handler qualifying later attributes with name.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &SlogHandler{attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+// addSlogAttr stores a in m, flattening groups into dotted keys.
+func addSlogAttr(m map[string]any, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			addSlogAttr(m, prefix, ga)
+		}
+		return
+	}
+	setField(m, prefix+a.Key, a.Value.Any())
+}
+
+// slogWriter hands logz entries to a slog.Handler. It implements the writer
+// interface accepted by the logz SetWriter.
+type slogWriter struct {
+	h slog.Handler
+}
+
+func (w *slogWriter) Write(entry any) error {
+	e, ok := entry.(lz.LogzEntry)
+	if !ok {
+		return fmt.Errorf("unsupported log entry type: %T", entry)
+	}
+	level := SlogLevel(string(e.GetLevel()))
+	ctx := context.Background()
+	if !w.h.Enabled(ctx, level) {
+		return nil
+	}
+	r := slog.NewRecord(e.GetTimestamp(), level, e.GetMessage(), 0)
+	for _, f := range entryFields(e) {
+		switch f.key {
+		case FieldTime, FieldLevel, FieldMessage:
+			continue
+		}
+		r.AddAttrs(slog.Any(f.key, f.value))
+	}
+	return w.h.Handle(ctx, r)
+}
+
+// SetSlogHandler sends the log output to h instead of stdout or the log
+// file, with the same fields as the JSON format. Log types map to slog
+// levels as SlogLevel does. A nil handler restores the previous output.
+func SetSlogHandler(h slog.Handler) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	slogOut = h
+	applyOutput()
+}
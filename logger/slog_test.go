@@ -0,0 +1,177 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogLevel(t *testing.T) {
+	tests := []struct {
+		logType string
+		level   slog.Level
+		back    LogType
+	}{
+		{"debug", slog.LevelDebug, LogTypeDebug},
+		{"notice", SlogLevelNotice, LogTypeNotice},
+		{"info", slog.LevelInfo, LogTypeInfo},
+		{"success", SlogLevelSuccess, LogTypeSuccess},
+		{"warn", slog.LevelWarn, LogTypeWarn},
+		{"error", slog.LevelError, LogTypeError},
+		{"FATAL", SlogLevelFatal, LogTypeError},
+		{"panic", SlogLevelFatal, LogTypeError},
+		{"unknown", slog.LevelInfo, LogTypeInfo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.logType, func(t *testing.T) {
+			if got := SlogLevel(tt.logType); got != tt.level {
+				t.Errorf("SlogLevel(%q) = %v, want %v", tt.logType, got, tt.level)
+			}
+			if got := LogTypeOf(tt.level); got != tt.back {
+				t.Errorf("LogTypeOf(%v) = %q, want %q", tt.level, got, tt.back)
+			}
+		})
+	}
+}
+
+func TestLogTypeOfRoundsDown(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  LogType
+	}{
+		{slog.LevelDebug - 4, LogTypeDebug},
+		{SlogLevelNotice - 1, LogTypeDebug},
+		{slog.LevelInfo - 1, LogTypeNotice},
+		{slog.LevelInfo + 1, LogTypeInfo},
+		{slog.LevelWarn - 1, LogTypeSuccess},
+		{slog.LevelError - 1, LogTypeWarn},
+		{slog.LevelError + 8, LogTypeError},
+	}
+	for _, tt := range tests {
+		if got := LogTypeOf(tt.level); got != tt.want {
+			t.Errorf("LogTypeOf(%v) = %q, want %q", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestSlogHandlerEnabled(t *testing.T) {
+	keepLevels(t)
+	if err := SetLevelSpec("warn,db=info"); err != nil {
+		t.Fatal(err)
+	}
+	plain := NewSlogHandler()
+	tests := []struct {
+		name    string
+		handler slog.Handler
+		level   slog.Level
+		want    bool
+	}{
+		// Without a module, the lowest level of any module applies.
+		{"plain debug", plain, slog.LevelDebug, false},
+		{"plain info", plain, slog.LevelInfo, true},
+		{"db info", plain.WithAttrs([]slog.Attr{slog.String(FieldModule, "db")}), slog.LevelInfo, true},
+		{"http info", plain.WithAttrs([]slog.Attr{slog.String(FieldModule, "http")}), slog.LevelInfo, false},
+		{"http warn", plain.WithAttrs([]slog.Attr{slog.String(FieldModule, "http")}), slog.LevelWarn, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.handler.Enabled(context.Background(), tt.level); got != tt.want {
+				t.Errorf("Enabled(%v) = %v, want %v", tt.level, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlogHandlerFields(t *testing.T) {
+	keepLevels(t)
+	if err := SetLevelSpec("debug"); err != nil {
+		t.Fatal(err)
+	}
+	read := capture(t, FormatJSON)
+
+	base := slog.New(NewSlogHandler()).With("a", 1)
+	grouped := base.WithGroup("g").With("b", 2)
+	grouped.Warn("grouped", "c", 3, slog.Group("sub", "d", 4))
+	base.Info("base", "e", 5)
+
+	lines := read()
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	tests := []struct {
+		line  string
+		level string
+		want  map[string]any
+		// absent are keys the line must not carry.
+		absent []string
+	}{
+		{lines[0], "warn", map[string]any{FieldMessage: "grouped", "a": 1.0, "g.b": 2.0, "g.c": 3.0, "g.sub.d": 4.0}, []string{"b", "c"}},
+		{lines[1], "info", map[string]any{FieldMessage: "base", "a": 1.0, "e": 5.0}, []string{"g.b", "b"}},
+	}
+	for _, tt := range tests {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(tt.line), &m); err != nil {
+			t.Fatalf("invalid JSON %q: %v", tt.line, err)
+		}
+		if m[FieldLevel] != tt.level {
+			t.Errorf("%s: level = %v, want %s", tt.line, m[FieldLevel], tt.level)
+		}
+		for k, v := range tt.want {
+			if m[k] != v {
+				t.Errorf("%s: %s = %v, want %v", tt.line, k, m[k], v)
+			}
+		}
+		for _, k := range tt.absent {
+			if _, ok := m[k]; ok {
+				t.Errorf("%s: unexpected key %s", tt.line, k)
+			}
+		}
+	}
+}
+
+func TestSetSlogHandler(t *testing.T) {
+	keepLevels(t)
+	if err := SetLevelSpec("debug"); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	SetSlogHandler(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: SlogLevelNotice}))
+	t.Cleanup(func() { SetSlogHandler(nil) })
+
+	Log("debug", "filtered by the handler")
+	Log("notice", "noticed")
+	LogKV("warn", "warned", "key", "value")
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		records = append(records, m)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2:\n%s", len(records), buf.String())
+	}
+	tests := []struct {
+		msg, level string
+		fields     map[string]any
+	}{
+		{"noticed", SlogLevelNotice.String(), nil},
+		{"warned", slog.LevelWarn.String(), map[string]any{"key": "value"}},
+	}
+	for i, tt := range tests {
+		r := records[i]
+		if r[slog.MessageKey] != tt.msg || r[slog.LevelKey] != tt.level {
+			t.Errorf("record %d = %v %v, want %s %s", i, r[slog.LevelKey], r[slog.MessageKey], tt.level, tt.msg)
+		}
+		for k, v := range tt.fields {
+			if r[k] != v {
+				t.Errorf("record %d: %s = %v, want %v", i, k, r[k], v)
+			}
+		}
+	}
+}
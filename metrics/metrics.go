@@ -0,0 +1,180 @@
+// Package metrics provides counters, gauges and histograms that modules
+// register into a registry exposed in the Prometheus text exposition
+// format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rafa-mori/goforge/paths"
+)
+
+// Metric is anything that can be exposed by a Registry.
+type Metric interface {
+	// Name returns the metric family name.
+	Name() string
+	// write renders the metric family in the text exposition format.
+	write(w *bufio.Writer)
+}
+
+var validName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// Namespace returns the prefix of the built-in metrics, derived from the
+// manifest bin.
+func Namespace() string {
+	return sanitize(paths.AppName())
+}
+
+// Name joins the namespace and parts into a metric name, e.g.
+// Name("jobs", "runs_total") is "goforge_jobs_runs_total".
+func Name(parts ...string) string {
+	return Namespace() + "_" + strings.Join(parts, "_")
+}
+
+func sanitize(s string) string {
+	var sb strings.Builder
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+			sb.WriteRune(r)
+		case r >= '0' && r <= '9' && i > 0:
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+	return sb.String()
+}
+
+// Registry holds a set of uniquely named metrics.
+type Registry struct {
+	mu      sync.RWMutex
+	metrics map[string]Metric
+}
+
+// Default is the registry the package-level constructors register into
+// and the one served by the HTTP server at /metrics.
+var Default = NewRegistry()
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]Metric)}
+}
+
+// Register adds m to the registry. It fails when the name is invalid or
+// already taken.
+func (r *Registry) Register(m Metric) error {
+	if !validName.MatchString(m.Name()) {
+		return fmt.Errorf("invalid metric name %q", m.Name())
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.metrics[m.Name()]; exists {
+		return fmt.Errorf("metric %s is already registered", m.Name())
+	}
+	r.metrics[m.Name()] = m
+	return nil
+}
+
+// MustRegister is Register that panics on error. It is meant for
+// package-level metric declarations.
+func (r *Registry) MustRegister(m Metric) {
+	if err := r.Register(m); err != nil {
+		panic(err)
+	}
+}
+
+// Unregister removes the named metric.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.metrics, name)
+}
+
+// WriteText writes every metric, sorted by name, in the Prometheus text
+// exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	list := make([]Metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		list = append(list, m)
+	}
+	r.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+
+	bw := bufio.NewWriter(w)
+	for _, m := range list {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the registry in the text exposition format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WriteText(w)
+	})
+}
+
+// Handler serves the Default registry.
+func Handler() http.Handler { return Default.Handler() }
+
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	if help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(help))
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+func writeSample(w *bufio.Writer, name string, labelNames, labelValues []string, extraName, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labelNames) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, ln := range labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, ln, escapeLabel(labelValues[i]))
+		}
+		if extraName != "" {
+			if len(labelNames) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, extraName, escapeLabel(extraValue))
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
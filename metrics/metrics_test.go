@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// isolate moves m from Default into a registry of its own.
+func isolate(t *testing.T, metrics ...Metric) *Registry {
+	t.Helper()
+	r := NewRegistry()
+	for _, m := range metrics {
+		Default.Unregister(m.Name())
+		if err := r.Register(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func TestWriteText(t *testing.T) {
+	reqs := NewCounter("test_requests_total", "Requests served.", "method", "code")
+	reqs.Inc("GET", "200")
+	reqs.Add(2, "GET", "200")
+	reqs.Inc("POST", "500")
+	idle := NewCounter("test_idle_total", "Never incremented.")
+	temp := NewGauge("test_temperature", "Line one\nback\\slash.", "room")
+	temp.Set(21.5, `say "hi"`)
+	temp.Dec("a\nb")
+	up := NewGaugeFunc("test_up", "", func() float64 { return 1 })
+	lat := NewHistogram("test_latency_seconds", "Latency.", []float64{1, 0.5}, "op")
+	lat.Observe(0.2, "read")
+	lat.Observe(0.7, "read")
+	lat.Observe(3, "read")
+
+	r := isolate(t, reqs, idle, temp, up, lat)
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_idle_total Never incremented.
+# TYPE test_idle_total counter
+test_idle_total 0
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{op="read",le="0.5"} 1
+test_latency_seconds_bucket{op="read",le="1"} 2
+test_latency_seconds_bucket{op="read",le="+Inf"} 3
+test_latency_seconds_sum{op="read"} 3.9
+test_latency_seconds_count{op="read"} 3
+# HELP test_requests_total Requests served.
+# TYPE test_requests_total counter
+test_requests_total{method="GET",code="200"} 3
+test_requests_total{method="POST",code="500"} 1
+# HELP test_temperature Line one\nback\\slash.
+# TYPE test_temperature gauge
+test_temperature{room="a\nb"} -1
+test_temperature{room="say \"hi\""} 21.5
+# TYPE test_up gauge
+test_up 1
+`
+	if got := b.String(); got != want {
+		t.Errorf("WriteText() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	inf, nan := 1.0, 0.0
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{0, "0"},
+		{1.5, "1.5"},
+		{1e21, "1e+21"},
+		{inf / 0, "+Inf"},
+		{-inf / 0, "-Inf"},
+		{nan / nan, "NaN"},
+	}
+	for _, tt := range tests {
+		if got := formatFloat(tt.v); got != tt.want {
+			t.Errorf("formatFloat(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"ok_total", false},
+		{"ns:sub_total", false},
+		{"9starts_with_digit", true},
+		{"has-dash", true},
+		{"", true},
+	}
+	r := NewRegistry()
+	for _, tt := range tests {
+		err := r.Register(&GaugeFunc{name: tt.name, kind: "gauge", fn: func() float64 { return 0 }})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Register(%q) error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+	if err := r.Register(&GaugeFunc{name: "ok_total", kind: "gauge"}); err == nil {
+		t.Error("duplicate name accepted")
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"goforge", "goforge"},
+		{"my-app.v2", "my_app_v2"},
+		{"2fa", "_fa"},
+	}
+	for _, tt := range tests {
+		if got := sanitize(tt.in); got != tt.want {
+			t.Errorf("sanitize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	c := NewCounter("test_handler_total", "")
+	r := isolate(t, c)
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "test_handler_total 0\n") {
+		t.Errorf("body = %q", rec.Body.String())
+	}
+}
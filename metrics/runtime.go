@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// startTime is used for the uptime metric.
+var startTime = time.Now()
+
+var (
+	memStatsMu   sync.Mutex
+	memStats     runtime.MemStats
+	memStatsRead time.Time
+)
+
+// readMemStats caches runtime.MemStats briefly so one scrape reading
+// several memory metrics stops the world only once.
+func readMemStats() *runtime.MemStats {
+	memStatsMu.Lock()
+	defer memStatsMu.Unlock()
+	if time.Since(memStatsRead) > time.Second {
+		runtime.ReadMemStats(&memStats)
+		memStatsRead = time.Now()
+	}
+	return &memStats
+}
+
+func init() {
+	NewGaugeFunc(Name("uptime_seconds"), "Seconds since the process started.", func() float64 {
+		return time.Since(startTime).Seconds()
+	})
+	NewGaugeFunc(Name("start_time_seconds"), "Start time of the process since the Unix epoch in seconds.", func() float64 {
+		return float64(startTime.UnixNano()) / 1e9
+	})
+	NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+	NewGaugeFunc("go_threads", "Number of OS threads created.", func() float64 {
+		n, _ := runtime.ThreadCreateProfile(nil)
+		return float64(n)
+	})
+	NewGaugeFunc("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", func() float64 {
+		return float64(readMemStats().Alloc)
+	})
+	NewGaugeFunc("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", func() float64 {
+		return float64(readMemStats().HeapInuse)
+	})
+	NewGaugeFunc("go_memstats_sys_bytes", "Number of bytes obtained from the system.", func() float64 {
+		return float64(readMemStats().Sys)
+	})
+	NewCounterFunc("go_memstats_mallocs_total", "Total number of heap objects allocated.", func() float64 {
+		return float64(readMemStats().Mallocs)
+	})
+	NewCounterFunc("go_gc_cycles_total", "Number of completed GC cycles.", func() float64 {
+		return float64(readMemStats().NumGC)
+	})
+	NewGaugeFunc("go_gc_pause_last_seconds", "Duration of the last GC pause.", func() float64 {
+		ms := readMemStats()
+		if ms.NumGC == 0 {
+			return 0
+		}
+		return float64(ms.PauseNs[(ms.NumGC+255)%256]) / 1e9
+	})
+	info := NewGauge("go_info", "Information about the Go environment.", "version")
+	info.Set(1, runtime.Version())
+}
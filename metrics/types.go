@@ -0,0 +1,276 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// labelSep joins label values into series keys; it cannot appear in
+// valid UTF-8 text.
+const labelSep = "\xff"
+
+// series stores one value per combination of label values.
+type series struct {
+	mu     sync.Mutex
+	labels []string
+	values map[string]*float64
+}
+
+func newSeries(labels []string) series {
+	return series{labels: labels, values: make(map[string]*float64)}
+}
+
+func (s *series) key(labelValues []string) string {
+	if len(labelValues) != len(s.labels) {
+		panic(fmt.Sprintf("metrics: expected %d label values, got %d", len(s.labels), len(labelValues)))
+	}
+	return strings.Join(labelValues, labelSep)
+}
+
+func (s *series) update(labelValues []string, fn func(float64) float64) {
+	k := s.key(labelValues)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.values[k]
+	if !ok {
+		v = new(float64)
+		s.values[k] = v
+	}
+	*v = fn(*v)
+}
+
+func (s *series) get(labelValues []string) float64 {
+	k := s.key(labelValues)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.values[k]; ok {
+		return *v
+	}
+	return 0
+}
+
+func (s *series) writeSamples(w *bufio.Writer, name string) {
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	values := make([]float64, len(keys))
+	for i, k := range keys {
+		values[i] = *s.values[k]
+	}
+	s.mu.Unlock()
+
+	// An unlabeled metric is always exposed, even before its first update.
+	if len(s.labels) == 0 && len(keys) == 0 {
+		writeSample(w, name, nil, nil, "", "", 0)
+		return
+	}
+	for i, k := range keys {
+		writeSample(w, name, s.labels, splitKey(k, len(s.labels)), "", "", values[i])
+	}
+}
+
+func splitKey(k string, n int) []string {
+	if n == 0 {
+		return nil
+	}
+	return strings.SplitN(k, labelSep, n)
+}
+
+// Counter is a monotonically increasing value, optionally partitioned by
+// labels.
+type Counter struct {
+	name, help string
+	series
+}
+
+// NewCounter creates a counter and registers it in Default.
+func NewCounter(name, help string, labelNames ...string) *Counter {
+	c := &Counter{name: name, help: help, series: newSeries(labelNames)}
+	Default.MustRegister(c)
+	return c
+}
+
+func (c *Counter) Name() string { return c.name }
+
+// Inc adds one to the counter of the given label values.
+func (c *Counter) Inc(labelValues ...string) { c.Add(1, labelValues...) }
+
+// Add adds v, which must not be negative, to the counter.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+	c.update(labelValues, func(cur float64) float64 { return cur + v })
+}
+
+// Value returns the current value for the given label values.
+func (c *Counter) Value(labelValues ...string) float64 { return c.get(labelValues) }
+
+func (c *Counter) write(w *bufio.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+	c.writeSamples(w, c.name)
+}
+
+// Gauge is a value that can go up and down, optionally partitioned by
+// labels.
+type Gauge struct {
+	name, help string
+	series
+}
+
+// NewGauge creates a gauge and registers it in Default.
+func NewGauge(name, help string, labelNames ...string) *Gauge {
+	g := &Gauge{name: name, help: help, series: newSeries(labelNames)}
+	Default.MustRegister(g)
+	return g
+}
+
+func (g *Gauge) Name() string { return g.name }
+
+// Set sets the gauge to v.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.update(labelValues, func(float64) float64 { return v })
+}
+
+// Add adds v, possibly negative, to the gauge.
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.update(labelValues, func(cur float64) float64 { return cur + v })
+}
+
+// Inc adds one to the gauge.
+func (g *Gauge) Inc(labelValues ...string) { g.Add(1, labelValues...) }
+
+// Dec subtracts one from the gauge.
+func (g *Gauge) Dec(labelValues ...string) { g.Add(-1, labelValues...) }
+
+// Value returns the current value for the given label values.
+func (g *Gauge) Value(labelValues ...string) float64 { return g.get(labelValues) }
+
+func (g *Gauge) write(w *bufio.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	g.writeSamples(w, g.name)
+}
+
+// GaugeFunc is a gauge whose value is computed when it is collected.
+type GaugeFunc struct {
+	name, help string
+	kind       string
+	fn         func() float64
+}
+
+// NewGaugeFunc creates a gauge backed by fn and registers it in Default.
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help, kind: "gauge", fn: fn}
+	Default.MustRegister(g)
+	return g
+}
+
+// NewCounterFunc creates a counter backed by fn, which must never
+// decrease, and registers it in Default.
+func NewCounterFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help, kind: "counter", fn: fn}
+	Default.MustRegister(g)
+	return g
+}
+
+func (g *GaugeFunc) Name() string { return g.name }
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	writeHeader(w, g.name, g.help, g.kind)
+	writeSample(w, g.name, nil, nil, "", "", g.fn())
+}
+
+// DefaultBuckets are the histogram buckets used when none are given,
+// suited to durations in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Histogram samples observations into cumulative buckets.
+type Histogram struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+
+	mu   sync.Mutex
+	data map[string]*histogramData
+}
+
+type histogramData struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram creates a histogram with the given upper bounds and
+// registers it in Default. nil buckets means DefaultBuckets.
+func NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	if math.IsInf(b[len(b)-1], 1) {
+		b = b[:len(b)-1]
+	}
+	h := &Histogram{name: name, help: help, labels: labelNames, buckets: b, data: make(map[string]*histogramData)}
+	Default.MustRegister(h)
+	return h
+}
+
+func (h *Histogram) Name() string { return h.name }
+
+// Observe records v.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	if len(labelValues) != len(h.labels) {
+		panic(fmt.Sprintf("metrics: expected %d label values, got %d", len(h.labels), len(labelValues)))
+	}
+	k := strings.Join(labelValues, labelSep)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	d, ok := h.data[k]
+	if !ok {
+		d = &histogramData{counts: make([]uint64, len(h.buckets))}
+		h.data[k] = d
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			d.counts[i]++
+		}
+	}
+	d.sum += v
+	d.count++
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+
+	h.mu.Lock()
+	keys := make([]string, 0, len(h.data))
+	for k := range h.data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	snapshot := make([]histogramData, len(keys))
+	for i, k := range keys {
+		d := h.data[k]
+		snapshot[i] = histogramData{counts: append([]uint64(nil), d.counts...), sum: d.sum, count: d.count}
+	}
+	h.mu.Unlock()
+
+	for i, k := range keys {
+		labelValues := splitKey(k, len(h.labels))
+		d := snapshot[i]
+		for j, upper := range h.buckets {
+			writeSample(w, h.name+"_bucket", h.labels, labelValues, "le", formatFloat(upper), float64(d.counts[j]))
+		}
+		writeSample(w, h.name+"_bucket", h.labels, labelValues, "le", "+Inf", float64(d.count))
+		writeSample(w, h.name+"_sum", h.labels, labelValues, "", "", d.sum)
+		writeSample(w, h.name+"_count", h.labels, labelValues, "", "", float64(d.count))
+	}
+}
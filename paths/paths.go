@@ -0,0 +1,106 @@
+// Package paths resolves the per-user directories used by the application.
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	manifest "github.com/rafa-mori/goforge/info"
+)
+
+// Every directory is namespaced by the manifest "bin" field and follows the
+// XDG base directory specification, falling back to the usual defaults under
+// the user's home directory.
+
+// AppName returns the name used to namespace directories and files.
+func AppName() string {
+	if info, err := manifest.GetManifest(); err == nil && info.GetBin() != "" {
+		return info.GetBin()
+	}
+	return "goforge"
+}
+
+// ConfigDir returns the directory holding the user configuration.
+func ConfigDir() string {
+	return filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), AppName())
+}
+
+// DataDir returns the directory for persistent application data.
+func DataDir() string {
+	return filepath.Join(xdgDir("XDG_DATA_HOME", ".local", "share"), AppName())
+}
+
+// CacheDir returns the directory for data that can be recomputed.
+func CacheDir() string {
+	return filepath.Join(xdgDir("XDG_CACHE_HOME", ".cache"), AppName())
+}
+
+// RuntimeDir returns the directory for runtime files such as the pidfile.
+// It lives under $XDG_RUNTIME_DIR when set, and in a per-user directory
+// under the system temporary directory otherwise.
+func RuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, AppName())
+	}
+	return filepath.Join(os.TempDir(), AppName()+"-"+strconv.Itoa(os.Getuid()))
+}
+
+// EnsureRuntimeDir creates RuntimeDir when missing and returns it. The
+// directory holds the pidfile and the control socket, so it is refused
+// unless it is a real directory owned by the current user with mode 0700:
+// under the shared temporary directory, another user could have created it
+// first.
+func EnsureRuntimeDir() (string, error) {
+	dir := RuntimeDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	if err := CheckRuntimeDir(); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// CheckRuntimeDir returns an error when RuntimeDir exists but is not safe to
+// use, as described in EnsureRuntimeDir. A missing directory is not an
+// error.
+func CheckRuntimeDir() error {
+	dir := RuntimeDir()
+	stat, err := os.Lstat(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if !stat.IsDir() {
+		return fmt.Errorf("runtime directory %s is not a directory", dir)
+	}
+	if st, ok := stat.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("runtime directory %s is owned by uid %d, not %d", dir, st.Uid, os.Getuid())
+	}
+	if perm := stat.Mode().Perm(); perm != 0o700 {
+		return fmt.Errorf("runtime directory %s has mode %#o, want 0700", dir, perm)
+	}
+	return nil
+}
+
+// PluginDir returns the directory scanned for out-of-process plugins.
+func PluginDir() string {
+	return filepath.Join(DataDir(), "plugins")
+}
+
+func xdgDir(env string, fallback ...string) string {
+	if dir := os.Getenv(env); dir != "" && filepath.IsAbs(dir) {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.TempDir()
+	}
+	return filepath.Join(append([]string{home}, fallback...)...)
+}
@@ -0,0 +1,86 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckRuntimeDir(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, dir string)
+		wantErr bool
+	}{
+		{name: "missing", setup: func(*testing.T, string) {}},
+		{
+			name:  "private directory",
+			setup: func(t *testing.T, dir string) { mkdir(t, dir, 0o700) },
+		},
+		{
+			name:    "group readable",
+			setup:   func(t *testing.T, dir string) { mkdir(t, dir, 0o750) },
+			wantErr: true,
+		},
+		{
+			name:    "world writable",
+			setup:   func(t *testing.T, dir string) { mkdir(t, dir, 0o777) },
+			wantErr: true,
+		},
+		{
+			name: "symlink to a private directory",
+			setup: func(t *testing.T, dir string) {
+				target := filepath.Join(filepath.Dir(dir), "target")
+				mkdir(t, target, 0o700)
+				if err := os.Symlink(target, dir); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "regular file",
+			setup: func(t *testing.T, dir string) {
+				if err := os.WriteFile(dir, nil, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+			tt.setup(t, RuntimeDir())
+			if err := CheckRuntimeDir(); (err != nil) != tt.wantErr {
+				t.Errorf("CheckRuntimeDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnsureRuntimeDir(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	dir, err := EnsureRuntimeDir()
+	if err != nil {
+		t.Fatalf("EnsureRuntimeDir() error = %v", err)
+	}
+	stat, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := stat.Mode().Perm(); perm != 0o700 {
+		t.Errorf("mode = %#o, want 0700", perm)
+	}
+}
+
+// mkdir creates dir with exactly perm, regardless of the umask.
+func mkdir(t *testing.T, dir string, perm os.FileMode) {
+	t.Helper()
+	if err := os.Mkdir(dir, perm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, perm); err != nil {
+		t.Fatal(err)
+	}
+}
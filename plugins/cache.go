@@ -0,0 +1,66 @@
+package plugins
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	gl "github.com/rafa-mori/goforge/logger"
+	"github.com/rafa-mori/goforge/paths"
+)
+
+// stamp identifies a version of a plugin executable.
+type stamp struct {
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mod_time"`
+}
+
+// cacheEntry is the cached metadata of a plugin executable.
+type cacheEntry struct {
+	Stamp    stamp    `json:"stamp"`
+	Metadata Metadata `json:"metadata"`
+}
+
+// cacheFile returns the file caching plugin metadata by executable path.
+func cacheFile() string {
+	return filepath.Join(paths.CacheDir(), "plugins.json")
+}
+
+func loadCache() map[string]cacheEntry {
+	data, err := os.ReadFile(cacheFile())
+	if err != nil {
+		return nil
+	}
+	var cache map[string]cacheEntry
+	if err := json.Unmarshal(data, &cache); err != nil {
+		gl.Logf("debug", "Ignoring invalid plugin cache: %v", err)
+		return nil
+	}
+	return cache
+}
+
+// saveCache replaces the cache with the metadata of plugins. Entries of
+// executables no longer found are dropped.
+func saveCache(plugins []*Plugin) {
+	cache := make(map[string]cacheEntry, len(plugins))
+	for _, p := range plugins {
+		cache[p.Path] = cacheEntry{Stamp: p.stamp, Metadata: p.Metadata}
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	file := cacheFile()
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		gl.Logf("debug", "Failed to create the plugin cache directory: %v", err)
+		return
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		gl.Logf("debug", "Failed to write the plugin cache: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		gl.Logf("debug", "Failed to write the plugin cache: %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+// Package plugins discovers out-of-process GoForge modules shipped as
+// standalone executables.
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rafa-mori/goforge"
+	gl "github.com/rafa-mori/goforge/logger"
+	"github.com/rafa-mori/goforge/paths"
+	"github.com/spf13/cobra"
+)
+
+// A plugin is any executable named "<bin>-<name>" (e.g. goforge-deploy)
+// found in the plugin directory or on PATH. When invoked with MetadataFlag
+// it must print a JSON document describing itself (see Metadata) and exit
+// zero. Plugins that fail the handshake are still exposed, with a generic
+// description.
+//
+// Discovery only lists the executables. The handshake runs when the
+// metadata is needed, i.e. when help is rendered, and its result is cached
+// per executable path, size and modification time.
+
+// MetadataFlag is the flag passed to a plugin to request its metadata.
+const MetadataFlag = "--goforge-metadata"
+
+const (
+	// handshakeTimeout bounds how long a plugin may take to answer MetadataFlag.
+	handshakeTimeout = 2 * time.Second
+	// pluginWaitDelay is how long a plugin may take to exit after being
+	// interrupted before it is killed.
+	pluginWaitDelay = 5 * time.Second
+)
+
+// Metadata mirrors the descriptive fields of the goforge.GoForge interface.
+// Usage is the argument synopsis shown after the command name.
+type Metadata struct {
+	Alias            string   `json:"alias,omitempty"`
+	ShortDescription string   `json:"short_description,omitempty"`
+	LongDescription  string   `json:"long_description,omitempty"`
+	Usage            string   `json:"usage,omitempty"`
+	Examples         []string `json:"examples,omitempty"`
+	Module           string   `json:"module,omitempty"`
+}
+
+// Plugin is an external executable exposed as a GoForge module. Until Load
+// is called, Metadata holds the cached metadata, or the defaults.
+type Plugin struct {
+	Name     string
+	Path     string
+	Metadata Metadata
+
+	stamp  stamp
+	cached bool
+	once   sync.Once
+}
+
+// ExitError reports a plugin that exited with a non-zero status. The status
+// is meant to become the exit status of the host command.
+type ExitError struct {
+	Name string
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("plugin %s exited with status %d", e.Name, e.Code)
+}
+
+// ExitCode returns the exit status of the plugin.
+func (e *ExitError) ExitCode() int { return e.Code }
+
+// Prefix returns the executable name prefix plugins must use.
+func Prefix() string {
+	return paths.AppName() + "-"
+}
+
+// Dirs returns the directories scanned for plugins, in lookup order: the
+// plugin directory first, then every PATH entry. Empty PATH entries are
+// skipped rather than read as the current directory, so that running a
+// command never executes files from wherever it was started.
+func Dirs() []string {
+	dirs := []string{paths.PluginDir()}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// Discover scans Dirs for plugin executables without running them. When the
+// same name is found more than once, the first match wins.
+func Discover() []*Plugin {
+	prefix := Prefix()
+	seen := make(map[string]bool)
+	found := make([]*Plugin, 0)
+	cache := loadCache()
+
+	for _, dir := range Dirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			fileName := entry.Name()
+			if !strings.HasPrefix(fileName, prefix) || len(fileName) == len(prefix) {
+				continue
+			}
+			name := strings.TrimPrefix(fileName, prefix)
+			if runtime.GOOS == "windows" {
+				// Dots are part of the name elsewhere, as in goforge-tool.v2.
+				name = strings.TrimSuffix(name, filepath.Ext(fileName))
+			}
+			if name == "" || seen[name] {
+				continue
+			}
+			path := filepath.Join(dir, fileName)
+			st, ok := executableStamp(path)
+			if !ok {
+				continue
+			}
+			seen[name] = true
+			p := &Plugin{Name: name, Path: path, stamp: st}
+			if c, ok := cache[path]; ok && c.Stamp == st {
+				p.Metadata, p.cached = c.Metadata, true
+			}
+			p.Metadata = withDefaults(p.Metadata, name, path)
+			found = append(found, p)
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].Name < found[j].Name })
+	return found
+}
+
+// executableStamp returns the cache stamp of path, and whether it is an
+// executable regular file.
+func executableStamp(path string) (stamp, bool) {
+	stat, err := os.Stat(path)
+	if err != nil || !stat.Mode().IsRegular() || stat.Mode().Perm()&0o111 == 0 {
+		return stamp{}, false
+	}
+	return stamp{Size: stat.Size(), ModTime: stat.ModTime().UnixNano()}, true
+}
+
+func withDefaults(md Metadata, name, path string) Metadata {
+	if md.Module == "" {
+		md.Module = name
+	}
+	if md.ShortDescription == "" {
+		md.ShortDescription = "Plugin provided by " + path
+	}
+	return md
+}
+
+// Load performs the metadata handshake, unless the metadata came from the
+// cache. It runs at most once per plugin.
+func (p *Plugin) Load() {
+	p.once.Do(func() {
+		if p.cached {
+			return
+		}
+		md, err := handshake(p.Path)
+		if err != nil {
+			gl.Logf("debug", "plugin %s: metadata handshake failed: %v", p.Name, err)
+			md = Metadata{}
+		}
+		p.Metadata = withDefaults(md, p.Name, p.Path)
+	})
+}
+
+// LoadAll loads the metadata of plugins concurrently and caches it for the
+// next runs.
+func LoadAll(plugins []*Plugin) {
+	var wg sync.WaitGroup
+	fresh := false
+	for _, p := range plugins {
+		if p.cached {
+			continue
+		}
+		fresh = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Load()
+		}()
+	}
+	wg.Wait()
+	if fresh {
+		saveCache(plugins)
+	}
+}
+
+func handshake(path string) (Metadata, error) {
+	var md Metadata
+
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, MetadataFlag)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return md, err
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &md); err != nil {
+		return md, fmt.Errorf("invalid metadata: %w", err)
+	}
+	return md, nil
+}
+
+func (p *Plugin) Alias() string            { return p.Metadata.Alias }
+func (p *Plugin) ShortDescription() string { return p.Metadata.ShortDescription }
+func (p *Plugin) LongDescription() string  { return p.Metadata.LongDescription }
+func (p *Plugin) Usage() string            { return p.Metadata.Usage }
+func (p *Plugin) Examples() []string       { return p.Metadata.Examples }
+func (p *Plugin) Active() bool             { return true }
+func (p *Plugin) Module() string           { return p.Metadata.Module }
+func (p *Plugin) Execute() error           { return p.ExecuteContext(context.Background()) }
+func (p *Plugin) ExecuteContext(ctx context.Context) error {
+	return p.run(ctx, os.Args[1:])
+}
+func (p *Plugin) Command() *cobra.Command {
+	use := p.Name
+	if p.Metadata.Usage != "" {
+		use = p.Name + " " + p.Metadata.Usage
+	}
+	return &cobra.Command{
+		Use:                use,
+		Short:              p.Metadata.ShortDescription,
+		Long:               p.Metadata.LongDescription,
+		DisableFlagParsing: true,
+		Annotations: map[string]string{
+			"plugin": p.Path,
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := p.run(cmd.Context(), args)
+			if _, ok := err.(*ExitError); ok {
+				// The plugin reported its own failure.
+				cmd.SilenceErrors = true
+			}
+			return err
+		},
+	}
+}
+
+func (p *Plugin) run(ctx context.Context, args []string) error {
+	cmd := exec.CommandContext(ctx, p.Path, args...)
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = pluginWaitDelay
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), strings.ToUpper(paths.AppName())+"_PLUGIN_NAME="+p.Name)
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+			return &ExitError{Name: p.Name, Code: exitErr.ExitCode()}
+		}
+		return fmt.Errorf("plugin %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+// Ensure Plugin satisfies the GoForge interface.
+var _ goforge.GoForge = (*Plugin)(nil)
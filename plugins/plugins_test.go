@@ -0,0 +1,146 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rafa-mori/goforge/paths"
+)
+
+// pluginScript answers the handshake with metadata, logging every
+// handshake to the file named by $HANDSHAKES, and otherwise exits with the
+// status given as its first argument.
+const pluginScript = `#!/bin/sh
+if [ "$1" = "--goforge-metadata" ]; then
+	echo x >> "$HANDSHAKES"
+	echo '{"alias":"dp","short_description":"Deploy things","usage":"<env>"}'
+	exit 0
+fi
+exit "${1:-0}"
+`
+
+// setup isolates the plugin lookup and the cache in temporary directories.
+// It returns the plugin directory, the single PATH entry and a function
+// counting handshakes.
+func setup(t *testing.T) (dir, bin string, handshakes func() int) {
+	t.Helper()
+	root := t.TempDir()
+	bin = filepath.Join(root, "bin")
+	t.Setenv("XDG_DATA_HOME", filepath.Join(root, "data"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(root, "cache"))
+	t.Setenv("PATH", bin)
+	log := filepath.Join(root, "handshakes")
+	t.Setenv("HANDSHAKES", log)
+	return paths.PluginDir(), bin, func() int {
+		data, _ := os.ReadFile(log)
+		return strings.Count(string(data), "x")
+	}
+}
+
+func writePlugin(t *testing.T, dir, name, script string, mode os.FileMode) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(script), mode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDiscover(t *testing.T) {
+	dir, bin, handshakes := setup(t)
+	prefix := Prefix()
+	first := writePlugin(t, dir, prefix+"deploy", pluginScript, 0o755)
+	writePlugin(t, bin, prefix+"deploy", pluginScript, 0o755)
+	dotted := writePlugin(t, bin, prefix+"tool.v2", pluginScript, 0o755)
+	writePlugin(t, dir, prefix+"notexec", pluginScript, 0o644)
+	writePlugin(t, dir, prefix, pluginScript, 0o755)
+	writePlugin(t, dir, "other-tool", pluginScript, 0o755)
+
+	found := Discover()
+	got := make(map[string]string, len(found))
+	for _, p := range found {
+		got[p.Name] = p.Path
+	}
+	want := map[string]string{"deploy": first, "tool.v2": dotted}
+	if len(got) != len(want) {
+		t.Fatalf("Discover() = %v, want %v", got, want)
+	}
+	for name, path := range want {
+		if got[name] != path {
+			t.Errorf("plugin %s = %q, want %q", name, got[name], path)
+		}
+	}
+	if n := handshakes(); n != 0 {
+		t.Errorf("Discover ran %d handshakes, want none", n)
+	}
+	if md := found[0].Metadata; md.Module != "deploy" || !strings.Contains(md.ShortDescription, first) {
+		t.Errorf("default metadata = %+v", md)
+	}
+}
+
+func TestHandshakeAndCache(t *testing.T) {
+	dir, _, handshakes := setup(t)
+	path := writePlugin(t, dir, Prefix()+"deploy", pluginScript, 0o755)
+	writePlugin(t, dir, Prefix()+"broken", "#!/bin/sh\nexit 1\n", 0o755)
+
+	load := func() map[string]Metadata {
+		found := Discover()
+		LoadAll(found)
+		mds := make(map[string]Metadata, len(found))
+		for _, p := range found {
+			mds[p.Name] = p.Metadata
+		}
+		return mds
+	}
+
+	mds := load()
+	if md := mds["deploy"]; md.Alias != "dp" || md.ShortDescription != "Deploy things" || md.Usage != "<env>" || md.Module != "deploy" {
+		t.Errorf("deploy metadata = %+v", md)
+	}
+	if md := mds["broken"]; md.Module != "broken" || md.Alias != "" {
+		t.Errorf("metadata after a failed handshake = %+v", md)
+	}
+	if n := handshakes(); n != 1 {
+		t.Fatalf("%d handshakes, want 1", n)
+	}
+
+	if md := load()["deploy"]; md.Alias != "dp" {
+		t.Errorf("cached metadata = %+v", md)
+	}
+	if n := handshakes(); n != 1 {
+		t.Errorf("%d handshakes after a cached run, want 1", n)
+	}
+
+	// A changed executable is handshaken again.
+	writePlugin(t, dir, filepath.Base(path), pluginScript+"\n", 0o755)
+	load()
+	if n := handshakes(); n != 2 {
+		t.Errorf("%d handshakes after the plugin changed, want 2", n)
+	}
+}
+
+func TestRunKeepsExitStatus(t *testing.T) {
+	dir, _, _ := setup(t)
+	path := writePlugin(t, dir, Prefix()+"deploy", pluginScript, 0o755)
+	p := &Plugin{Name: "deploy", Path: path}
+
+	if err := p.run(context.Background(), []string{"0"}); err != nil {
+		t.Errorf("run() = %v, want nil", err)
+	}
+	var exitErr *ExitError
+	if err := p.run(context.Background(), []string{"3"}); !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Errorf("run() = %v, want an ExitError with status 3", err)
+	}
+
+	p.Path = filepath.Join(dir, "missing")
+	if err := p.run(context.Background(), nil); err == nil || errors.As(err, &exitErr) {
+		t.Errorf("run() of a missing executable = %v, want a plain error", err)
+	}
+}
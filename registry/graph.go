@@ -0,0 +1,198 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rafa-mori/goforge"
+)
+
+// ErrMissingDependency is returned when a module depends on a module that
+// is not part of the graph.
+var ErrMissingDependency = errors.New("missing dependency")
+
+// CycleError is returned when module dependencies form a cycle.
+type CycleError struct {
+	// Path lists the modules in the cycle, starting and ending with the
+	// same module.
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return "dependency cycle: " + strings.Join(e.Path, " -> ")
+}
+
+// Graph is the dependency graph of a set of modules. Edges point from a
+// module to the modules it depends on.
+type Graph struct {
+	nodes []string
+	deps  map[string][]string
+}
+
+// Dependencies returns the names a module depends on, or nil when it does
+// not implement goforge.Dependent.
+func Dependencies(m goforge.GoForge) []string {
+	if d, ok := m.(goforge.Dependent); ok {
+		return d.Dependencies()
+	}
+	return nil
+}
+
+// NewGraph builds a graph from module names and their dependencies. Node
+// order is preserved and used to break ties when sorting.
+func NewGraph(names []string, deps map[string][]string) *Graph {
+	g := &Graph{deps: make(map[string][]string, len(names))}
+	for _, name := range names {
+		if _, exists := g.deps[name]; exists {
+			continue
+		}
+		g.nodes = append(g.nodes, name)
+		g.deps[name] = append([]string(nil), deps[name]...)
+	}
+	return g
+}
+
+// ModuleGraph builds the graph of the given modules.
+func ModuleGraph(mods []goforge.GoForge) *Graph {
+	names := make([]string, 0, len(mods))
+	deps := make(map[string][]string, len(mods))
+	for _, m := range mods {
+		names = append(names, m.Module())
+		deps[m.Module()] = Dependencies(m)
+	}
+	return NewGraph(names, deps)
+}
+
+// Nodes returns the module names in the graph.
+func (g *Graph) Nodes() []string { return append([]string(nil), g.nodes...) }
+
+// DependenciesOf returns the direct dependencies of name.
+func (g *Graph) DependenciesOf(name string) []string {
+	return append([]string(nil), g.deps[name]...)
+}
+
+// Validate checks that every dependency exists and that there are no
+// cycles.
+func (g *Graph) Validate() error {
+	_, err := g.Levels()
+	return err
+}
+
+// Sort returns the modules in dependency order: every module comes after
+// all of its dependencies.
+func (g *Graph) Sort() ([]string, error) {
+	levels, err := g.Levels()
+	if err != nil {
+		return nil, err
+	}
+	sorted := make([]string, 0, len(g.nodes))
+	for _, level := range levels {
+		sorted = append(sorted, level...)
+	}
+	return sorted, nil
+}
+
+// Levels groups the modules into startup waves. Modules in a wave only
+// depend on modules of earlier waves, so each wave can be started
+// concurrently once the previous one is up.
+func (g *Graph) Levels() ([][]string, error) {
+	for _, name := range g.nodes {
+		for _, dep := range g.deps[name] {
+			if _, ok := g.deps[dep]; !ok {
+				return nil, fmt.Errorf("module %s depends on %s: %w", name, dep, ErrMissingDependency)
+			}
+		}
+	}
+
+	remaining := make(map[string]int, len(g.nodes))
+	for _, name := range g.nodes {
+		remaining[name] = len(g.deps[name])
+	}
+	dependents := make(map[string][]string, len(g.nodes))
+	for _, name := range g.nodes {
+		for _, dep := range g.deps[name] {
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	levels := make([][]string, 0)
+	done := 0
+	for done < len(g.nodes) {
+		level := make([]string, 0)
+		for _, name := range g.nodes {
+			if remaining[name] == 0 {
+				level = append(level, name)
+			}
+		}
+		if len(level) == 0 {
+			return nil, &CycleError{Path: g.findCycle(remaining)}
+		}
+		for _, name := range level {
+			remaining[name] = -1
+			for _, dependent := range dependents[name] {
+				remaining[dependent]--
+			}
+		}
+		done += len(level)
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// findCycle walks the unresolved nodes until one repeats.
+func (g *Graph) findCycle(remaining map[string]int) []string {
+	var start string
+	for _, name := range g.nodes {
+		if remaining[name] > 0 {
+			start = name
+			break
+		}
+	}
+	visited := make(map[string]int)
+	path := make([]string, 0)
+	for current := start; ; {
+		if idx, seen := visited[current]; seen {
+			return append(path[idx:], current)
+		}
+		visited[current] = len(path)
+		path = append(path, current)
+		for _, dep := range g.deps[current] {
+			if remaining[dep] > 0 {
+				current = dep
+				break
+			}
+		}
+	}
+}
+
+// String renders the graph as indented text, one module per line followed
+// by its dependencies.
+func (g *Graph) String() string {
+	var sb strings.Builder
+	for _, name := range g.nodes {
+		sb.WriteString(name)
+		sb.WriteString("\n")
+		for _, dep := range g.deps[name] {
+			sb.WriteString("  -> " + dep + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// DOT renders the graph in Graphviz DOT format.
+func (g *Graph) DOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph modules {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	for _, name := range g.nodes {
+		sb.WriteString(fmt.Sprintf("  %q;\n", name))
+	}
+	for _, name := range g.nodes {
+		for _, dep := range g.deps[name] {
+			sb.WriteString(fmt.Sprintf("  %q -> %q;\n", name, dep))
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
@@ -0,0 +1,115 @@
+package registry
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestGraphLevels(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []string
+		deps  map[string][]string
+		want  [][]string
+	}{
+		{
+			name:  "no dependencies",
+			nodes: []string{"b", "a"},
+			want:  [][]string{{"b", "a"}},
+		},
+		{
+			name:  "chain",
+			nodes: []string{"http", "db", "cache"},
+			deps:  map[string][]string{"http": {"cache"}, "cache": {"db"}},
+			want:  [][]string{{"db"}, {"cache"}, {"http"}},
+		},
+		{
+			name:  "diamond",
+			nodes: []string{"app", "left", "right", "base"},
+			deps:  map[string][]string{"app": {"left", "right"}, "left": {"base"}, "right": {"base"}},
+			want:  [][]string{{"base"}, {"left", "right"}, {"app"}},
+		},
+		{
+			name:  "duplicate node keeps the first",
+			nodes: []string{"a", "b", "a"},
+			deps:  map[string][]string{"a": {"b"}},
+			want:  [][]string{{"b"}, {"a"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewGraph(tt.nodes, tt.deps).Levels()
+			if err != nil {
+				t.Fatalf("Levels() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Levels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphSort(t *testing.T) {
+	g := NewGraph([]string{"http", "db", "cache"}, map[string][]string{"http": {"cache", "db"}, "cache": {"db"}})
+	got, err := g.Sort()
+	if err != nil {
+		t.Fatalf("Sort() error = %v", err)
+	}
+	if want := []string{"db", "cache", "http"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Sort() = %v, want %v", got, want)
+	}
+}
+
+func TestGraphErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		nodes     []string
+		deps      map[string][]string
+		missing   bool
+		wantCycle []string
+	}{
+		{
+			name:    "missing dependency",
+			nodes:   []string{"a"},
+			deps:    map[string][]string{"a": {"ghost"}},
+			missing: true,
+		},
+		{
+			name:      "self dependency",
+			nodes:     []string{"a"},
+			deps:      map[string][]string{"a": {"a"}},
+			wantCycle: []string{"a", "a"},
+		},
+		{
+			name:      "two-node cycle",
+			nodes:     []string{"a", "b"},
+			deps:      map[string][]string{"a": {"b"}, "b": {"a"}},
+			wantCycle: []string{"a", "b", "a"},
+		},
+		{
+			name:      "cycle behind a valid node",
+			nodes:     []string{"root", "x", "y", "z"},
+			deps:      map[string][]string{"root": {"x"}, "x": {"y"}, "y": {"z"}, "z": {"x"}},
+			wantCycle: []string{"x", "y", "z", "x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewGraph(tt.nodes, tt.deps).Validate()
+			if tt.missing {
+				if !errors.Is(err, ErrMissingDependency) {
+					t.Fatalf("Validate() error = %v, want ErrMissingDependency", err)
+				}
+				return
+			}
+			var cycle *CycleError
+			if !errors.As(err, &cycle) {
+				t.Fatalf("Validate() error = %v, want a CycleError", err)
+			}
+			if !reflect.DeepEqual(cycle.Path, tt.wantCycle) {
+				t.Errorf("cycle = %v, want %v", cycle.Path, tt.wantCycle)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Package registry keeps track of the GoForge modules linked into the binary.
+package registry
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/rafa-mori/goforge"
+	gl "github.com/rafa-mori/goforge/logger"
+)
+
+// Modules register themselves from their own packages, usually inside an
+// init function, and the root command mounts every active module it finds
+// here. Importing the module package (even with a blank import) is enough
+// to make it part of the binary:
+//
+//	func init() {
+//		registry.Register(&MyModule{})
+//	}
+
+var (
+	mu      sync.RWMutex
+	modules = make(map[string]goforge.GoForge)
+	order   []string
+)
+
+// Register adds a module to the registry. Modules are keyed by Module();
+// registering a nil module, a module without a name or a name that is
+// already taken is logged and ignored.
+func Register(m goforge.GoForge) {
+	if m == nil {
+		gl.Log("error", "registry: cannot register a nil module")
+		return
+	}
+	name := m.Module()
+	if name == "" {
+		gl.Log("error", "registry: cannot register a module without a name")
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, exists := modules[name]; exists {
+		gl.Log("warn", "registry: module "+name+" is already registered, ignoring")
+		return
+	}
+	modules[name] = m
+	order = append(order, name)
+	gl.Log("debug", "registry: module "+name+" registered")
+}
+
+// Unregister removes a module from the registry. It reports whether the
+// module was registered.
+func Unregister(name string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, exists := modules[name]; !exists {
+		return false
+	}
+	delete(modules, name)
+	for i, n := range order {
+		if n == name {
+			order = append(order[:i], order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Lookup returns the module registered under name.
+func Lookup(name string) (goforge.GoForge, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	m, ok := modules[name]
+	return m, ok
+}
+
+// Modules returns every registered module in registration order.
+func Modules() []goforge.GoForge {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	list := make([]goforge.GoForge, 0, len(order))
+	for _, name := range order {
+		list = append(list, modules[name])
+	}
+	return list
+}
+
+// ActiveModules returns the registered modules whose Active() is true,
+// in registration order.
+func ActiveModules() []goforge.GoForge {
+	list := make([]goforge.GoForge, 0)
+	for _, m := range Modules() {
+		if m.Active() {
+			list = append(list, m)
+		}
+	}
+	return list
+}
+
+// Names returns the sorted names of every registered module.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(order))
+	names = append(names, order...)
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,80 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseNext(t *testing.T) {
+	// A Sunday.
+	from := time.Date(2026, 3, 15, 10, 30, 45, 0, time.UTC)
+	at := func(month time.Month, day, hour, min, sec int) time.Time {
+		year := 2026
+		if month < 3 {
+			year = 2027
+		}
+		return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+	}
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", at(3, 15, 10, 31, 0)},
+		{"*/15 * * * *", at(3, 15, 10, 45, 0)},
+		{"0 * * * *", at(3, 15, 11, 0, 0)},
+		{"5-10/2 * * * *", at(3, 15, 11, 5, 0)},
+		{"0,40 10 * * *", at(3, 15, 10, 40, 0)},
+		{"30 2 * * *", at(3, 16, 2, 30, 0)},
+		{"0 9 * * mon-fri", at(3, 16, 9, 0, 0)},
+		{"0 0 * * 7", at(3, 22, 0, 0, 0)},
+		{"0 0 1 * *", at(4, 1, 0, 0, 0)},
+		{"0 0 31 * *", at(3, 31, 0, 0, 0)},
+		{"0 12 * jan *", at(1, 1, 12, 0, 0)},
+		// Day-of-month and day-of-week both restricted: either matches.
+		{"0 0 13 * fri", at(3, 20, 0, 0, 0)},
+		{"0 0 ? * SUN", at(3, 22, 0, 0, 0)},
+		{"*/10 * * * * *", at(3, 15, 10, 30, 50)},
+		{"@hourly", at(3, 15, 11, 0, 0)},
+		{"@daily", at(3, 16, 0, 0, 0)},
+		{"@MONTHLY", at(4, 1, 0, 0, 0)},
+		{"@every 90s", at(3, 15, 10, 32, 15)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.spec, err)
+			}
+			if got := s.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"@every -1s",
+		"@every soon",
+		"@fortnightly",
+	} {
+		t.Run(spec, func(t *testing.T) {
+			if _, err := Parse(spec); err == nil {
+				t.Errorf("Parse(%q) succeeded, want an error", spec)
+			}
+		})
+	}
+}
@@ -0,0 +1,368 @@
+// Package scheduler runs periodic jobs inside the service started by the
+// start command. Modules register jobs with Register, usually from init,
+// using cron expressions or fixed intervals; see Parse.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	gl "github.com/rafa-mori/goforge/logger"
+	"github.com/rafa-mori/goforge/metrics"
+)
+
+var (
+	// ErrUnknownJob is returned for a job name that is not registered.
+	ErrUnknownJob = errors.New("unknown job")
+	// ErrJobRunning is returned when a job that does not allow overlapping
+	// runs is triggered while it is still running.
+	ErrJobRunning = errors.New("job is already running")
+	// ErrNotRunning is returned by Trigger when the scheduler is not
+	// started.
+	ErrNotRunning = errors.New("scheduler is not running")
+)
+
+var (
+	jobRuns     = metrics.NewCounter(metrics.Name("job_runs_total"), "Job runs by job and outcome.", "job", "outcome")
+	jobDuration = metrics.NewHistogram(metrics.Name("job_duration_seconds"), "Duration of job runs.", nil, "job")
+)
+
+// Job is a unit of periodic work.
+type Job struct {
+	// Name identifies the job in logs and in the jobs command.
+	Name string
+	// Schedule is a cron expression, a macro such as @daily or
+	// "@every <duration>"; see Parse.
+	Schedule string
+	// Run does the work. ctx is cancelled on timeout and on shutdown.
+	Run func(ctx context.Context) error
+	// Timeout bounds a single run; zero means no timeout.
+	Timeout time.Duration
+	// Jitter delays each scheduled run by a random duration in [0, Jitter),
+	// spreading the load of jobs sharing a schedule.
+	Jitter time.Duration
+	// AllowOverlap lets a run start while the previous one is still
+	// running. By default such runs are skipped.
+	AllowOverlap bool
+}
+
+// Status describes a registered job.
+type Status struct {
+	Name         string        `json:"name"`
+	Schedule     string        `json:"schedule"`
+	Next         time.Time     `json:"next"`
+	Running      bool          `json:"running"`
+	Runs         int           `json:"runs"`
+	LastRun      time.Time     `json:"last_run,omitempty"`
+	LastDuration time.Duration `json:"last_duration,omitempty"`
+	LastError    string        `json:"last_error,omitempty"`
+}
+
+type entry struct {
+	job      Job
+	schedule Schedule
+
+	running      int
+	next         time.Time
+	runs         int
+	lastRun      time.Time
+	lastDuration time.Duration
+	lastErr      error
+}
+
+// Scheduler runs registered jobs on their schedules, from Start to Stop.
+type Scheduler struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	order   []string
+	deps    []string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Default is the scheduler jobs are registered with by Register.
+var Default = New()
+
+// New returns an empty Scheduler.
+func New() *Scheduler {
+	return &Scheduler{entries: make(map[string]*entry)}
+}
+
+// Register adds job to the Default scheduler.
+func Register(job Job) error { return Default.Register(job) }
+
+// Register adds job to the scheduler. When the scheduler is already
+// running the job is scheduled right away.
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" {
+		return errors.New("job name is required")
+	}
+	if job.Run == nil {
+		return fmt.Errorf("job %s: Run is required", job.Name)
+	}
+	schedule, err := Parse(job.Schedule)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.Name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.entries[job.Name]; exists {
+		return fmt.Errorf("job %s is already registered", job.Name)
+	}
+	e := &entry{job: job, schedule: schedule, next: schedule.Next(time.Now())}
+	s.entries[job.Name] = e
+	s.order = append(s.order, job.Name)
+	if s.ctx != nil {
+		s.loop(e)
+	}
+	return nil
+}
+
+// DependsOn names the modules the jobs use. No job runs before they are up.
+func (s *Scheduler) DependsOn(modules ...string) {
+	s.deps = append(s.deps, modules...)
+}
+
+// Dependencies implements goforge.Dependent.
+func (s *Scheduler) Dependencies() []string { return s.deps }
+
+// Jobs returns the status of every job in registration order.
+func (s *Scheduler) Jobs() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Status, 0, len(s.order))
+	for _, name := range s.order {
+		e := s.entries[name]
+		st := Status{
+			Name:         name,
+			Schedule:     e.job.Schedule,
+			Next:         e.next,
+			Running:      e.running > 0,
+			Runs:         e.runs,
+			LastRun:      e.lastRun,
+			LastDuration: e.lastDuration,
+		}
+		if e.lastErr != nil {
+			st.LastError = e.lastErr.Error()
+		}
+		list = append(list, st)
+	}
+	return list
+}
+
+// Run triggers the named job now and waits for it to finish. It honours
+// the job's timeout and overlap policy.
+func (s *Scheduler) Run(ctx context.Context, name string) error {
+	s.mu.Lock()
+	e, ok := s.entries[name]
+	schedCtx := s.ctx
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownJob, name)
+	}
+	if schedCtx != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = mergeCancel(ctx, schedCtx)
+		defer cancel()
+	}
+	return s.run(ctx, e, "manual")
+}
+
+// Trigger starts the named job now in the background and returns at once.
+// The run is bound to the scheduler like the scheduled ones: it honours the
+// job's timeout and overlap policy, is cancelled by Stop and waited for.
+// Its outcome is reported by Jobs.
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownJob, name)
+	}
+	if s.ctx == nil || s.ctx.Err() != nil {
+		return ErrNotRunning
+	}
+	if err := s.reserve(e); err != nil {
+		return err
+	}
+	ctx := s.ctx
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		_ = s.runReserved(ctx, e, "manual")
+	}()
+	return nil
+}
+
+// Init is a no-op; jobs are validated when they are registered.
+func (s *Scheduler) Init() error { return nil }
+
+// Start schedules every registered job.
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	for _, name := range s.order {
+		s.loop(s.entries[name])
+	}
+	gl.Logf("debug", "Scheduler started with %d jobs", len(s.order))
+	return nil
+}
+
+// Stop stops scheduling and waits for the running jobs, whose context is
+// cancelled, until ctx's deadline.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("jobs still running: %w", ctx.Err())
+	}
+}
+
+// Health reports nil; a failing job is logged and shown by the jobs
+// command but does not make the service unhealthy.
+func (s *Scheduler) Health() error { return nil }
+
+// loop schedules e until the scheduler stops. s.mu must be held.
+func (s *Scheduler) loop(e *entry) {
+	ctx := s.ctx
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			s.mu.Lock()
+			next := e.schedule.Next(time.Now())
+			e.next = next
+			s.mu.Unlock()
+			if next.IsZero() {
+				gl.Log("warn", "Job "+e.job.Name+" has no future run time")
+				return
+			}
+
+			timer := time.NewTimer(time.Until(next) + jitter(e.job.Jitter))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				if err := s.run(ctx, e, "schedule"); errors.Is(err, ErrJobRunning) {
+					gl.Log("warn", "Job "+e.job.Name+" skipped: previous run still in progress")
+				}
+			}()
+		}
+	}()
+}
+
+// jitter returns a random delay in [0, spread), or zero when spread is
+// not positive.
+func jitter(spread time.Duration) time.Duration {
+	if spread <= 0 {
+		return 0
+	}
+	return rand.N(spread)
+}
+
+// run executes one run of e, recording and logging its outcome.
+func (s *Scheduler) run(ctx context.Context, e *entry, trigger string) error {
+	s.mu.Lock()
+	err := s.reserve(e)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.runReserved(ctx, e, trigger)
+}
+
+// reserve counts a new run of e, unless it would overlap a running one
+// that the job does not allow. s.mu must be held.
+func (s *Scheduler) reserve(e *entry) error {
+	if e.running > 0 && !e.job.AllowOverlap {
+		jobRuns.Inc(e.job.Name, "skipped")
+		return fmt.Errorf("%w: %s", ErrJobRunning, e.job.Name)
+	}
+	e.running++
+	return nil
+}
+
+// runReserved runs e once reserve has counted the run.
+func (s *Scheduler) runReserved(ctx context.Context, e *entry, trigger string) (err error) {
+	name := e.job.Name
+
+	if e.job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.job.Timeout)
+		defer cancel()
+	}
+
+	lgr := gl.FromContext(ctx).With("job", name, "trigger", trigger)
+	ctx = gl.IntoContext(ctx, lgr)
+	lgr.Log("debug", "Job started")
+	started := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			lgr.LogKV("debug", "Job panicked", "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+		}
+		elapsed := time.Since(started)
+		jobDuration.Observe(elapsed.Seconds(), name)
+
+		s.mu.Lock()
+		e.running--
+		e.runs++
+		e.lastRun = started
+		e.lastDuration = elapsed
+		e.lastErr = err
+		s.mu.Unlock()
+
+		if err != nil {
+			jobRuns.Inc(name, "error")
+			lgr.LogKV("error", "Job failed", "duration", elapsed.String(), "error", err.Error())
+			return
+		}
+		jobRuns.Inc(name, "success")
+		lgr.LogKV("info", "Job finished", "duration", elapsed.String())
+	}()
+
+	err = e.job.Run(ctx)
+	if e.job.Timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", e.job.Timeout)
+	}
+	return err
+}
+
+// mergeCancel returns a context derived from ctx that is also cancelled
+// when other is done.
+func mergeCancel(ctx, other context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(other, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
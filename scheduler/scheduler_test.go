@@ -0,0 +1,148 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingJob returns a job that blocks until its context is done, and a
+// channel receiving a value when a run starts.
+func blockingJob(name string) (Job, <-chan struct{}) {
+	started := make(chan struct{}, 4)
+	return Job{
+		Name:     name,
+		Schedule: "@yearly",
+		Run: func(ctx context.Context) error {
+			started <- struct{}{}
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}, started
+}
+
+func TestTrigger(t *testing.T) {
+	s := New()
+	job, started := blockingJob("slow")
+	if err := s.Register(job); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Trigger("slow"); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("Trigger() before Start error = %v, want ErrNotRunning", err)
+	}
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Trigger("missing"); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("Trigger(missing) error = %v, want ErrUnknownJob", err)
+	}
+	if err := s.Trigger("slow"); err != nil {
+		t.Fatalf("Trigger() error = %v", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("triggered job did not start")
+	}
+	if err := s.Trigger("slow"); !errors.Is(err, ErrJobRunning) {
+		t.Errorf("overlapping Trigger() error = %v, want ErrJobRunning", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	st := s.Jobs()[0]
+	if st.Running || st.Runs != 1 || st.LastError == "" {
+		t.Errorf("status after Stop = %+v, want one cancelled run", st)
+	}
+}
+
+// TestRunDuringStart exercises Run concurrently with Start under -race.
+func TestRunDuringStart(t *testing.T) {
+	s := New()
+	if err := s.Register(Job{Name: "quick", Schedule: "@yearly", AllowOverlap: true, Run: func(context.Context) error { return nil }}); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Run(context.Background(), "quick"); err != nil {
+				t.Errorf("Run() error = %v", err)
+			}
+		}()
+	}
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for _, spread := range []time.Duration{-time.Second, 0} {
+		if got := jitter(spread); got != 0 {
+			t.Errorf("jitter(%s) = %s, want 0", spread, got)
+		}
+	}
+	const spread = time.Second
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 1000; i++ {
+		d := jitter(spread)
+		if d < 0 || d >= spread {
+			t.Fatalf("jitter(%s) = %s, want [0, %s)", spread, d, spread)
+		}
+		seen[d] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("jitter(%s) always returned the same delay", spread)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	failing := errors.New("failing")
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		run     func(ctx context.Context) error
+		want    string
+	}{
+		{"blocks until the deadline", 20 * time.Millisecond, func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}, "timed out after 20ms"},
+		{"ignores the deadline", 20 * time.Millisecond, func(context.Context) error {
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		}, "timed out after 20ms"},
+		{"fails in time", time.Second, func(context.Context) error { return failing }, "failing"},
+		{"succeeds in time", time.Second, func(context.Context) error { return nil }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			if err := s.Register(Job{Name: "job", Schedule: "@yearly", Timeout: tt.timeout, Run: tt.run}); err != nil {
+				t.Fatal(err)
+			}
+			err := s.Run(context.Background(), "job")
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.want {
+				t.Errorf("Run() = %q, want %q", got, tt.want)
+			}
+			if status := s.Jobs()[0]; status.LastError != tt.want || status.Runs != 1 {
+				t.Errorf("status = %+v, want one run with error %q", status, tt.want)
+			}
+		})
+	}
+}
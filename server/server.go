@@ -0,0 +1,266 @@
+// Package server provides the HTTP server run by the start command. It
+// serves the built-in /healthz, /readyz, /version and /metrics endpoints
+// plus any route registered by modules through Handle.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rafa-mori/goforge/config"
+	"github.com/rafa-mori/goforge/health"
+	gl "github.com/rafa-mori/goforge/logger"
+	"github.com/rafa-mori/goforge/metrics"
+	vs "github.com/rafa-mori/goforge/version"
+)
+
+// DefaultAddr is the address the server listens on when none is configured.
+const DefaultAddr = "127.0.0.1:8080"
+
+// ReadinessCheck reports the state of the components the server depends
+// on, keyed by component name. A nil error means ready.
+type ReadinessCheck func() map[string]error
+
+// Configuration keys read by the start command.
+const (
+	ConfigKeyEnabled = "server.enabled"
+	ConfigKeyAddr    = "server.addr"
+)
+
+func init() {
+	config.SetDefault(ConfigKeyEnabled, true)
+	config.SetDefault(ConfigKeyAddr, DefaultAddr)
+}
+
+var (
+	routesMu sync.RWMutex
+	routes   = make(map[string]http.Handler)
+)
+
+// Handle registers a route served by every Server created afterwards.
+// Modules call it from their Init to expose their own endpoints.
+func Handle(pattern string, handler http.Handler) {
+	routesMu.Lock()
+	defer routesMu.Unlock()
+	routes[pattern] = handler
+}
+
+// HandleFunc registers a handler function as a route, see Handle.
+func HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	Handle(pattern, http.HandlerFunc(handler))
+}
+
+// Server is an HTTP server implementing goforge.Lifecycle.
+type Server struct {
+	addr      string
+	deps      []string
+	readiness ReadinessCheck
+
+	srv      *http.Server
+	listener net.Listener
+	serveErr atomic.Pointer[error]
+	ready    atomic.Bool
+}
+
+// New returns a Server listening on addr, or DefaultAddr when addr is "".
+func New(addr string) *Server {
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	return &Server{addr: addr}
+}
+
+// Addr returns the address the server listens on. Once started it is the
+// actual address, which matters when the configured port is 0.
+func (s *Server) Addr() string {
+	if s.listener != nil {
+		return s.listener.Addr().String()
+	}
+	return s.addr
+}
+
+// DependsOn names the modules that must be up before the server takes
+// traffic.
+func (s *Server) DependsOn(modules ...string) {
+	s.deps = append(s.deps, modules...)
+}
+
+// Dependencies implements goforge.Dependent.
+func (s *Server) Dependencies() []string { return s.deps }
+
+// SetReadinessCheck sets the check consulted by /readyz.
+func (s *Server) SetReadinessCheck(check ReadinessCheck) {
+	s.readiness = check
+}
+
+// Init builds the router.
+func (s *Server) Init() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealthz)
+	mux.HandleFunc("GET /readyz", s.handleReadyz)
+	mux.HandleFunc("GET /version", s.handleVersion)
+	mux.Handle("GET /metrics", metrics.Handler())
+
+	routesMu.RLock()
+	for pattern, handler := range routes {
+		mux.Handle(pattern, handler)
+	}
+	routesMu.RUnlock()
+
+	s.srv = &http.Server{
+		Addr:              s.addr,
+		Handler:           logRequests(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return nil
+}
+
+// Start binds the listener and serves requests in the background.
+func (s *Server) Start(ctx context.Context) error {
+	if s.srv == nil {
+		if err := s.Init(); err != nil {
+			return err
+		}
+	}
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", s.addr, err)
+	}
+	s.listener = ln
+	s.srv.BaseContext = func(net.Listener) context.Context { return ctx }
+
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			gl.Log("error", "HTTP server failed: "+err.Error())
+			s.serveErr.Store(&err)
+			s.ready.Store(false)
+		}
+	}()
+
+	s.ready.Store(true)
+	gl.Log("info", "HTTP server listening on "+s.Addr())
+	return nil
+}
+
+// Stop stops accepting requests and waits for in-flight requests until
+// ctx is done.
+func (s *Server) Stop(ctx context.Context) error {
+	s.ready.Store(false)
+	if s.srv == nil {
+		return nil
+	}
+	if err := s.srv.Shutdown(ctx); err != nil {
+		_ = s.srv.Close()
+		return err
+	}
+	return nil
+}
+
+// Health returns the error that stopped the server, if any.
+func (s *Server) Health() error {
+	if err := s.serveErr.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	report := health.Run(r.Context(), health.Liveness)
+	report.AddErrors(map[string]error{"http": s.Health()})
+	writeReport(w, report)
+}
+
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	report := health.Run(r.Context(), health.Readiness)
+	errs := make(map[string]error)
+	if s.readiness != nil {
+		errs = s.readiness()
+	}
+	if !s.ready.Load() {
+		errs["http"] = errors.New("not serving")
+	} else if _, ok := errs["http"]; !ok {
+		errs["http"] = nil
+	}
+	report.AddErrors(errs)
+	writeReport(w, report)
+}
+
+// writeReport answers 200 unless a critical check failed.
+func writeReport(w http.ResponseWriter, report *health.Report) {
+	status := http.StatusOK
+	if !report.Healthy() {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, _ *http.Request) {
+	svc := vs.GetService()
+	writeJSON(w, http.StatusOK, map[string]string{
+		"name":       svc.GetName(),
+		"version":    svc.GetCurrentVersion(),
+		"repository": svc.GetRepository(),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// requestDuration observes every request served, by method and status.
+var requestDuration = metrics.NewHistogram(metrics.Name("http_request_duration_seconds"), "Duration of HTTP requests served.", nil, "method", "code")
+
+// RequestIDHeader carries the request ID. An ID sent by the client, such as
+// one set by a proxy, is kept; otherwise one is generated.
+const RequestIDHeader = "X-Request-ID"
+
+// logRequests logs every request and puts a logger scoped to it in the
+// request context, so handlers log with its request ID through
+// logger.FromContext(r.Context()).
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		lgr := gl.FromContext(r.Context()).With("request_id", id)
+		r = r.WithContext(gl.IntoContext(r.Context(), lgr))
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		elapsed := time.Since(start)
+		requestDuration.Observe(elapsed.Seconds(), r.Method, strconv.Itoa(rec.status))
+		lgr.LogKV("debug", "HTTP request served",
+			"method", r.Method, "path", r.URL.Path, "status", rec.status, "duration", elapsed.String())
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
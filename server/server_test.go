@@ -0,0 +1,202 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/rafa-mori/goforge/health"
+	gl "github.com/rafa-mori/goforge/logger"
+	"github.com/rafa-mori/goforge/metrics"
+	vs "github.com/rafa-mori/goforge/version"
+)
+
+// withChecks replaces the built-in health checks, some of which reach the
+// network, with checks for the duration of the test.
+func withChecks(t *testing.T, checks ...health.Check) {
+	t.Helper()
+	saved := health.Default
+	health.Default = health.NewRegistry()
+	t.Cleanup(func() { health.Default = saved })
+	for _, c := range checks {
+		if err := health.Register(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func check(name string, kind health.Kind, err error) health.Check {
+	return health.Check{Name: name, Kind: kind, Critical: true, Run: func(context.Context) error { return err }}
+}
+
+// serve answers a GET request to path with a server that is ready when
+// ready is set.
+func serve(t *testing.T, s *Server, ready bool, path string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	s.ready.Store(ready)
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	s.srv.Handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeReport(t *testing.T, rec *httptest.ResponseRecorder) map[string]health.Result {
+	t.Helper()
+	var report health.Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	results := make(map[string]health.Result, len(report.Checks))
+	for _, r := range report.Checks {
+		results[r.Name] = r
+	}
+	return results
+}
+
+func TestProbes(t *testing.T) {
+	failing := errors.New("failing")
+	tests := []struct {
+		name      string
+		path      string
+		checks    []health.Check
+		ready     bool
+		readiness ReadinessCheck
+		want      int
+		// failed is the check expected to fail, if any.
+		failed string
+	}{
+		{"healthz ok", "/healthz", []health.Check{check("live", health.Liveness, nil)}, true, nil, http.StatusOK, ""},
+		{"healthz failing check", "/healthz", []health.Check{check("live", health.Liveness, failing)}, true, nil, http.StatusServiceUnavailable, "live"},
+		{"healthz ignores readiness", "/healthz", []health.Check{check("ready", health.Readiness, failing)}, false, nil, http.StatusOK, ""},
+		{"readyz ok", "/readyz", []health.Check{check("ready", health.Readiness, nil)}, true, nil, http.StatusOK, ""},
+		{"readyz not serving", "/readyz", nil, false, nil, http.StatusServiceUnavailable, "http"},
+		{"readyz failing check", "/readyz", []health.Check{check("ready", health.Readiness, failing)}, true, nil, http.StatusServiceUnavailable, "ready"},
+		{"readyz failing module", "/readyz", nil, true, func() map[string]error { return map[string]error{"db": failing} }, http.StatusServiceUnavailable, "db"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withChecks(t, tt.checks...)
+			s := New("")
+			s.SetReadinessCheck(tt.readiness)
+			rec := serve(t, s, tt.ready, tt.path, nil)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			results := decodeReport(t, rec)
+			if _, ok := results["http"]; !ok {
+				t.Error("report has no http check")
+			}
+			for name, r := range results {
+				if failed := r.Status == health.StatusFail; failed != (name == tt.failed) {
+					t.Errorf("check %s status = %s", name, r.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestVersion(t *testing.T) {
+	rec := serve(t, New(""), true, "/version", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var body map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	svc := vs.GetService()
+	want := map[string]string{"name": svc.GetName(), "version": svc.GetCurrentVersion(), "repository": svc.GetRepository()}
+	for k, v := range want {
+		if body[k] != v {
+			t.Errorf("%s = %q, want %q", k, body[k], v)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	withChecks(t)
+	serve(t, New(""), true, "/healthz", nil)
+	rec := serve(t, New(""), true, "/metrics", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	want := metrics.Name("http_request_duration_seconds") + `_count{method="GET",code="200"}`
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("metrics do not contain %s:\n%s", want, rec.Body)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	HandleFunc("GET /test/request-id", func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = gl.FromContext(r.Context()).Fields()["request_id"].(string)
+	})
+	generated := regexp.MustCompile(`^[0-9a-f]{16}$`)
+	tests := []struct {
+		name string
+		sent string
+		keep bool
+	}{
+		{"generated", "", false},
+		{"kept", "from-proxy", true},
+		{"too long", strings.Repeat("x", 129), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.sent != "" {
+				header.Set(RequestIDHeader, tt.sent)
+			}
+			rec := serve(t, New(""), true, "/test/request-id", header)
+			id := rec.Header().Get(RequestIDHeader)
+			if tt.keep && id != tt.sent {
+				t.Errorf("%s = %q, want %q", RequestIDHeader, id, tt.sent)
+			}
+			if !tt.keep && !generated.MatchString(id) {
+				t.Errorf("%s = %q, want a generated ID", RequestIDHeader, id)
+			}
+			if seen != id {
+				t.Errorf("handler saw request ID %q, want %q", seen, id)
+			}
+		})
+	}
+}
+
+func TestStartAndStop(t *testing.T) {
+	withChecks(t)
+	s := New("127.0.0.1:0")
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get("http://" + s.Addr() + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("/readyz status = %d, want 200", resp.StatusCode)
+	}
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Health(); err != nil {
+		t.Errorf("Health() after Stop = %v", err)
+	}
+	if _, err := http.Get("http://" + s.Addr() + "/readyz"); err == nil {
+		t.Error("server still serving after Stop")
+	}
+}
@@ -0,0 +1,226 @@
+// Package supervisor runs a command as a child process and restarts it
+// when it crashes, with exponential backoff and a limit on the number of
+// restarts within a time window.
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rafa-mori/goforge/config"
+	"github.com/rafa-mori/goforge/daemon"
+	gl "github.com/rafa-mori/goforge/logger"
+)
+
+// ErrTooManyRestarts is returned when the child crashed more than
+// MaxRestarts times within Window.
+var ErrTooManyRestarts = errors.New("too many restarts")
+
+// Configuration keys of the restart policy, used when no flag overrides it.
+const (
+	ConfigKeyInitialBackoff = "supervisor.initial_backoff"
+	ConfigKeyMaxBackoff     = "supervisor.max_backoff"
+	ConfigKeyMaxRestarts    = "supervisor.max_restarts"
+	ConfigKeyWindow         = "supervisor.window"
+	ConfigKeyStopTimeout    = "supervisor.stop_timeout"
+)
+
+func init() {
+	config.SetDefault(ConfigKeyInitialBackoff, "1s")
+	config.SetDefault(ConfigKeyMaxBackoff, "1m")
+	config.SetDefault(ConfigKeyMaxRestarts, 5)
+	config.SetDefault(ConfigKeyWindow, "5m")
+	config.SetDefault(ConfigKeyStopTimeout, "30s")
+}
+
+// forcedStopDelay is how long the child may take to exit when the
+// supervisor is forced to exit, before it is killed.
+const forcedStopDelay = time.Second
+
+// forwardedSignals are relayed to the child as they are received.
+// SIGINT and SIGTERM are handled through the context instead.
+var forwardedSignals = []os.Signal{syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGUSR2, syscall.SIGQUIT}
+
+// Options tunes the restart policy.
+type Options struct {
+	// InitialBackoff is the delay before the first restart. It doubles
+	// after every crash up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// MaxRestarts is how many crashes are tolerated within Window before
+	// giving up. A child that stays up for a whole Window also resets the
+	// backoff.
+	MaxRestarts int
+	Window      time.Duration
+	// StopTimeout is how long the child may take to exit after SIGTERM
+	// before it is killed.
+	StopTimeout time.Duration
+}
+
+// OptionsFromConfig reads the restart policy from the supervisor section.
+func OptionsFromConfig() Options {
+	c := config.Get()
+	return Options{
+		InitialBackoff: c.GetDuration(ConfigKeyInitialBackoff),
+		MaxBackoff:     c.GetDuration(ConfigKeyMaxBackoff),
+		MaxRestarts:    c.GetInt(ConfigKeyMaxRestarts),
+		Window:         c.GetDuration(ConfigKeyWindow),
+		StopTimeout:    c.GetDuration(ConfigKeyStopTimeout),
+	}
+}
+
+func (o Options) withDefaults() Options {
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = time.Second
+	}
+	if o.MaxBackoff < o.InitialBackoff {
+		o.MaxBackoff = o.InitialBackoff
+	}
+	if o.MaxRestarts <= 0 {
+		o.MaxRestarts = 5
+	}
+	if o.Window <= 0 {
+		o.Window = 5 * time.Minute
+	}
+	if o.StopTimeout <= 0 {
+		o.StopTimeout = 30 * time.Second
+	}
+	return o
+}
+
+// Supervisor keeps one command running.
+type Supervisor struct {
+	path string
+	args []string
+	opts Options
+	// after waits out the restart backoff. Tests replace it.
+	after func(time.Duration) <-chan time.Time
+}
+
+// New returns a Supervisor for the command path with args.
+func New(path string, args []string, opts Options) *Supervisor {
+	return &Supervisor{path: path, args: args, opts: opts.withDefaults(), after: time.After}
+}
+
+// Run starts the child and restarts it on crashes until ctx is done, the
+// child exits successfully or the restart limit is hit. When ctx is done
+// the child receives SIGTERM and is killed after StopTimeout.
+func (s *Supervisor) Run(ctx context.Context) error {
+	sigs := make(chan os.Signal, 4)
+	signal.Notify(sigs, forwardedSignals...)
+	defer signal.Stop(sigs)
+
+	backoff := s.opts.InitialBackoff
+	var crashes []time.Time
+
+	for attempt := 1; ; attempt++ {
+		startedAt := time.Now()
+		gl.Logf("info", "supervisor: starting %s (attempt %d)", s.path, attempt)
+		exitErr, err := s.runOnce(ctx, sigs)
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			gl.Log("info", "supervisor: child stopped, exiting")
+			return nil
+		}
+		if exitErr == nil {
+			gl.Log("info", "supervisor: child exited successfully, not restarting")
+			return nil
+		}
+
+		now := time.Now()
+		uptime := now.Sub(startedAt).Truncate(time.Millisecond)
+		gl.Logf("error", "supervisor: child crashed after %s: %v", uptime, exitErr)
+
+		if uptime >= s.opts.Window {
+			backoff = s.opts.InitialBackoff
+		}
+		crashes = append(pruneBefore(crashes, now.Add(-s.opts.Window)), now)
+		if len(crashes) > s.opts.MaxRestarts {
+			gl.Logf("error", "supervisor: %d crashes within %s, giving up", len(crashes), s.opts.Window)
+			return fmt.Errorf("%w: %d crashes within %s, last: %v", ErrTooManyRestarts, len(crashes), s.opts.Window, exitErr)
+		}
+
+		gl.Logf("warn", "supervisor: restarting in %s (%d/%d restarts within %s)", backoff, len(crashes), s.opts.MaxRestarts, s.opts.Window)
+		select {
+		case <-ctx.Done():
+			gl.Log("info", "supervisor: stopped while waiting to restart")
+			return nil
+		case <-s.after(backoff):
+		}
+		backoff = min(backoff*2, s.opts.MaxBackoff)
+	}
+}
+
+// runOnce runs the child until it exits. exitErr is the child's exit
+// status; startErr is set when the child could not be started at all.
+func (s *Supervisor) runOnce(ctx context.Context, sigs <-chan os.Signal) (exitErr, startErr error) {
+	cmd := exec.Command(s.path, s.args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// A separate process group keeps terminal signals from reaching the
+	// child directly; they are forwarded exactly once from here.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", s.path, err)
+	}
+	gl.Logf("debug", "supervisor: child running with pid %d", cmd.Process.Pid)
+
+	exited := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		exited <- cmd.Wait()
+		close(done)
+	}()
+
+	// The child has its own process group, so nothing else stops it when
+	// the supervisor is forced to exit. It already got SIGTERM when ctx was
+	// done; a second one forces its exit too, with its own cleanup.
+	defer daemon.AtExit(func() {
+		_ = cmd.Process.Signal(syscall.SIGTERM)
+		select {
+		case <-done:
+		case <-time.After(forcedStopDelay):
+			_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+	})()
+
+	for {
+		select {
+		case err := <-exited:
+			return err, nil
+		case sig := <-sigs:
+			gl.Log("debug", "supervisor: forwarding "+sig.String()+" to child")
+			_ = cmd.Process.Signal(sig)
+		case <-ctx.Done():
+			gl.Log("info", "supervisor: stopping child")
+			_ = cmd.Process.Signal(syscall.SIGTERM)
+			select {
+			case err := <-exited:
+				return err, nil
+			case <-time.After(s.opts.StopTimeout):
+				gl.Logf("warn", "supervisor: child did not exit within %s, killing it", s.opts.StopTimeout)
+				_ = cmd.Process.Kill()
+				return <-exited, nil
+			}
+		}
+	}
+}
+
+func pruneBefore(times []time.Time, cutoff time.Time) []time.Time {
+	kept := times[:0]
+	for _, t := range times {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,94 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// recordDelays makes s restart immediately and returns the backoffs it
+// waited out. After stopAfter restarts the wait never ends and cancel is
+// called, so that Run returns.
+func recordDelays(s *Supervisor, stopAfter int, cancel context.CancelFunc) *[]time.Duration {
+	var delays []time.Duration
+	s.after = func(d time.Duration) <-chan time.Time {
+		delays = append(delays, d)
+		if len(delays) >= stopAfter {
+			cancel()
+			return nil
+		}
+		ch := make(chan time.Time, 1)
+		ch <- time.Now()
+		return ch
+	}
+	return &delays
+}
+
+func TestBackoffDoublesUntilTooManyRestarts(t *testing.T) {
+	s := New("sh", []string{"-c", "exit 1"}, Options{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     4 * time.Millisecond,
+		MaxRestarts:    4,
+		Window:         time.Hour,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	delays := recordDelays(s, 100, cancel)
+
+	err := s.Run(ctx)
+	if !errors.Is(err, ErrTooManyRestarts) {
+		t.Fatalf("Run() = %v, want ErrTooManyRestarts", err)
+	}
+	want := []time.Duration{1, 2, 4, 4}
+	for i := range want {
+		want[i] *= time.Millisecond
+	}
+	if !slices.Equal(*delays, want) {
+		t.Errorf("backoffs = %v, want %v", *delays, want)
+	}
+}
+
+func TestBackoffResetsAfterWindow(t *testing.T) {
+	// Every run outlives the window, so the backoff never grows and
+	// earlier crashes never count towards MaxRestarts.
+	s := New("sh", []string{"-c", "sleep 0.05; exit 1"}, Options{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Second,
+		MaxRestarts:    1,
+		Window:         10 * time.Millisecond,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	delays := recordDelays(s, 4, cancel)
+
+	if err := s.Run(ctx); err != nil {
+		t.Fatalf("Run() = %v, want nil", err)
+	}
+	want := []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond}
+	if !slices.Equal(*delays, want) {
+		t.Errorf("backoffs = %v, want %v", *delays, want)
+	}
+}
+
+func TestSuccessfulExitIsNotRestarted(t *testing.T) {
+	s := New("sh", []string{"-c", "exit 0"}, Options{InitialBackoff: time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	delays := recordDelays(s, 100, cancel)
+
+	if err := s.Run(ctx); err != nil {
+		t.Fatalf("Run() = %v, want nil", err)
+	}
+	if len(*delays) != 0 {
+		t.Errorf("restarted %d times, want 0", len(*delays))
+	}
+}
+
+func TestStartFailure(t *testing.T) {
+	s := New("/nonexistent/command", nil, Options{})
+	if err := s.Run(context.Background()); err == nil {
+		t.Fatal("Run() = nil for a command that cannot start")
+	}
+}
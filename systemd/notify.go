@@ -0,0 +1,76 @@
+// Package systemd integrates the service with systemd: it renders and
+// installs unit files and implements the sd_notify protocol.
+package systemd
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Notification states understood by systemd.
+const (
+	StateReady     = "READY=1"
+	StateStopping  = "STOPPING=1"
+	StateReloading = "RELOADING=1"
+	StateWatchdog  = "WATCHDOG=1"
+)
+
+// Notify sends state to the socket named by $NOTIFY_SOCKET. It reports
+// false, without error, when the process is not supervised by systemd.
+func Notify(state string) (bool, error) {
+	socketAddr := os.Getenv("NOTIFY_SOCKET")
+	if socketAddr == "" {
+		return false, nil
+	}
+	// A leading "@" denotes a socket in the abstract namespace.
+	if socketAddr[0] == '@' {
+		socketAddr = "\x00" + socketAddr[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketAddr, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns the watchdog timeout configured for this
+// process, or 0 when the watchdog is disabled.
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// RunWatchdog sends WATCHDOG=1 at half the configured interval until ctx
+// is done. healthy, when non-nil, is consulted before each ping so a
+// wedged process stops feeding the watchdog and gets restarted.
+func RunWatchdog(ctx context.Context, healthy func() bool) {
+	interval := WatchdogInterval()
+	if interval == 0 {
+		return
+	}
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if healthy == nil || healthy() {
+				_, _ = Notify(StateWatchdog)
+			}
+		}
+	}
+}
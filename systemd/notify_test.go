@@ -0,0 +1,101 @@
+package systemd
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// listenNotify binds a datagram socket and points NOTIFY_SOCKET at it.
+func listenNotify(t *testing.T) *net.UnixConn {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	return conn
+}
+
+func receive(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	buf := make([]byte, 256)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("no notification received: %v", err)
+	}
+	return string(buf[:n])
+}
+
+func TestNotify(t *testing.T) {
+	for _, state := range []string{StateReady, StateReloading, StateStopping, StateWatchdog} {
+		t.Run(state, func(t *testing.T) {
+			conn := listenNotify(t)
+			sent, err := Notify(state)
+			if err != nil || !sent {
+				t.Fatalf("Notify(%q) = %v, %v", state, sent, err)
+			}
+			if got := receive(t, conn); got != state {
+				t.Errorf("payload = %q, want %q", got, state)
+			}
+		})
+	}
+}
+
+func TestNotifyWithoutSocket(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if sent, err := Notify(StateReady); sent || err != nil {
+		t.Errorf("Notify() = %v, %v, want false, nil", sent, err)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	tests := []struct {
+		name string
+		usec string
+		pid  string
+		want time.Duration
+	}{
+		{name: "disabled", want: 0},
+		{name: "invalid", usec: "soon", want: 0},
+		{name: "enabled", usec: "30000000", want: 30 * time.Second},
+		{name: "this process", usec: "1000", pid: strconv.Itoa(os.Getpid()), want: time.Millisecond},
+		{name: "another process", usec: "1000", pid: "1", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WATCHDOG_USEC", tt.usec)
+			t.Setenv("WATCHDOG_PID", tt.pid)
+			if got := WatchdogInterval(); got != tt.want {
+				t.Errorf("WatchdogInterval() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunWatchdog(t *testing.T) {
+	conn := listenNotify(t)
+	t.Setenv("WATCHDOG_USEC", "20000")
+	t.Setenv("WATCHDOG_PID", "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		RunWatchdog(ctx, func() bool { return true })
+		close(done)
+	}()
+	for i := 0; i < 2; i++ {
+		if got := receive(t, conn); got != StateWatchdog {
+			t.Errorf("payload = %q, want %q", got, StateWatchdog)
+		}
+	}
+	cancel()
+	<-done
+}
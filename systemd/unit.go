@@ -0,0 +1,204 @@
+package systemd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	manifest "github.com/rafa-mori/goforge/info"
+	gl "github.com/rafa-mori/goforge/logger"
+	"github.com/rafa-mori/goforge/paths"
+)
+
+// Unit describes the service unit rendered from the manifest.
+type Unit struct {
+	Description   string
+	Documentation string
+	ExecStart     string
+	WantedBy      string
+	User          bool
+}
+
+var unitTemplate = template.Must(template.New("unit").Parse(`[Unit]
+Description={{.Description}}
+{{- if .Documentation}}
+Documentation={{.Documentation}}
+{{- end}}
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=notify
+NotifyAccess=main
+ExecStart={{.ExecStart}}
+ExecReload=/bin/kill -HUP $MAINPID
+Restart=on-failure
+RestartSec=5s
+WatchdogSec=30s
+TimeoutStopSec=30s
+
+[Install]
+WantedBy={{.WantedBy}}
+`))
+
+// NewUnit builds the unit of the current executable from the manifest.
+func NewUnit(user bool) (*Unit, error) {
+	info, err := manifest.GetManifest()
+	if err != nil {
+		return nil, err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	return newUnit(info.GetName(), info.GetDescription(), info.GetHomepage(), exe, user), nil
+}
+
+// newUnit builds the unit running exe from the manifest fields. The
+// description falls back to the name.
+func newUnit(name, description, homepage, exe string, user bool) *Unit {
+	if description == "" {
+		description = name
+	}
+	wantedBy := "multi-user.target"
+	if user {
+		wantedBy = "default.target"
+	}
+	return &Unit{
+		Description:   escapeSpecifiers(description),
+		Documentation: escapeSpecifiers(homepage),
+		ExecStart:     ExecCommand(exe, "start"),
+		WantedBy:      wantedBy,
+		User:          user,
+	}
+}
+
+// ExecCommand returns a command line for ExecStart and similar settings,
+// quoting the arguments that systemd would otherwise split or expand.
+func ExecCommand(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteExecArg(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// quoteExecArg quotes arg following the systemd command line syntax:
+// "%" and "$" are doubled so that specifiers and variables are not
+// expanded, and arguments with spaces, quotes or backslashes are wrapped in
+// double quotes with backslash escapes.
+func quoteExecArg(arg string) string {
+	arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\;") {
+		return arg
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range arg {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// escapeSpecifiers doubles "%" so that systemd does not expand specifiers in
+// a free-form setting, and folds line breaks that would end it.
+func escapeSpecifiers(s string) string {
+	return strings.NewReplacer("%", "%%", "\r", " ", "\n", " ").Replace(s)
+}
+
+// Render returns the unit file content.
+func (u *Unit) Render() (string, error) {
+	var buf bytes.Buffer
+	if err := unitTemplate.Execute(&buf, u); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// UnitName returns the name of the unit, derived from the manifest bin.
+func UnitName() string {
+	return paths.AppName() + ".service"
+}
+
+// UnitPath returns where the unit file is installed.
+func UnitPath(user bool) string {
+	if user {
+		return filepath.Join(filepath.Dir(paths.ConfigDir()), "systemd", "user", UnitName())
+	}
+	return filepath.Join("/etc/systemd/system", UnitName())
+}
+
+// Install writes the unit file and reloads systemd.
+func Install(user bool) (string, error) {
+	u, err := NewUnit(user)
+	if err != nil {
+		return "", err
+	}
+	content, err := u.Render()
+	if err != nil {
+		return "", err
+	}
+	path := UnitPath(user)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return "", err
+	}
+	return path, systemctl(user, "daemon-reload")
+}
+
+// Uninstall stops and disables the unit, removes its file and reloads
+// systemd.
+func Uninstall(user bool) (string, error) {
+	path := UnitPath(user)
+	if _, err := os.Stat(path); err != nil {
+		return path, fmt.Errorf("unit %s is not installed: %w", path, err)
+	}
+	if err := systemctl(user, "disable", "--now", UnitName()); err != nil {
+		gl.Log("warn", "Failed to disable "+UnitName()+": "+err.Error())
+	}
+	if err := os.Remove(path); err != nil {
+		return path, err
+	}
+	return path, systemctl(user, "daemon-reload")
+}
+
+// Enable enables the unit and starts it now.
+func Enable(user bool) error {
+	return systemctl(user, "enable", "--now", UnitName())
+}
+
+// Disable stops the unit and disables it.
+func Disable(user bool) error {
+	return systemctl(user, "disable", "--now", UnitName())
+}
+
+func systemctl(user bool, args ...string) error {
+	if user {
+		args = append([]string{"--user"}, args...)
+	}
+	out, err := exec.Command("systemctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl %v: %w: %s", args, err, bytes.TrimSpace(out))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package systemd
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExecCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "plain", args: []string{"/usr/bin/goforge", "start"}, want: "/usr/bin/goforge start"},
+		{name: "space", args: []string{"/opt/my apps/goforge", "start"}, want: `"/opt/my apps/goforge" start`},
+		{name: "quote and backslash", args: []string{`/opt/a"b\c`, "start"}, want: `"/opt/a\"b\\c" start`},
+		{name: "specifier", args: []string{"/opt/100%/goforge"}, want: "/opt/100%%/goforge"},
+		{name: "variable", args: []string{"/opt/$HOME/goforge"}, want: "/opt/$$HOME/goforge"},
+		{name: "semicolon", args: []string{"/bin/x", ";"}, want: `/bin/x ";"`},
+		{name: "empty argument", args: []string{"/bin/x", ""}, want: `/bin/x ""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExecCommand(tt.args...); got != tt.want {
+				t.Errorf("ExecCommand(%q) = %s, want %s", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestRenderGolden(t *testing.T) {
+	tests := []struct {
+		golden                      string
+		name, description, homepage string
+		exe                         string
+		user                        bool
+	}{
+		{"system.service", "goforge", "GoForge service", "https://github.com/rafa-mori/goforge", "/usr/bin/goforge", false},
+		{"user.service", "goforge", "", "", "/home/me/bin/goforge", true},
+		{"escaped.service", "goforge", "100% up\nand running", "https://example.com/%41", "/opt/100%/go forge", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			got, err := newUnit(tt.name, tt.description, tt.homepage, tt.exe, tt.user).Render()
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("Render() mismatch, run with -update to accept:\n--- got\n%s\n--- want\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package tasks
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	gl "github.com/rafa-mori/goforge/logger"
+)
+
+// ErrLocked is returned when another process already has the queue open.
+var ErrLocked = errors.New("task queue is in use by another process")
+
+// Journal operations.
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// record is one line of the journal. A put stores the full state of a
+// task, a delete removes it; replaying the records in order rebuilds the
+// queue.
+type record struct {
+	Op   string `json:"op"`
+	Task *Task  `json:"task,omitempty"`
+	ID   string `json:"id,omitempty"`
+}
+
+// journal is the append-only file backing a queue. Every record is synced
+// to disk before the call that wrote it returns. A lock file next to the
+// journal keeps a second process from writing to it.
+type journal struct {
+	path    string
+	lock    *os.File
+	f       *os.File
+	records int
+}
+
+// openJournal locks and replays the journal at path, creating it when
+// missing, and compacts it when it holds mostly stale records.
+func openJournal(path string) (*journal, map[string]*Task, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, nil, err
+	}
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = lock.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, nil, ErrLocked
+		}
+		return nil, nil, err
+	}
+
+	tasks, records, err := readJournal(path)
+	if err != nil {
+		_ = lock.Close()
+		return nil, nil, err
+	}
+	j := &journal{path: path, lock: lock, records: records}
+	if err := j.compact(tasks); err != nil {
+		_ = lock.Close()
+		return nil, nil, err
+	}
+	return j, tasks, nil
+}
+
+// readJournal replays the journal at path without locking it. A missing
+// journal is an empty queue. Lines that cannot be decoded, such as a
+// record torn by a crash, are skipped.
+func readJournal(path string) (map[string]*Task, int, error) {
+	tasks := make(map[string]*Task)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return tasks, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = f.Close() }()
+
+	records := 0
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 16<<20)
+	for line := 1; sc.Scan(); line++ {
+		var rec record
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			gl.Logf("warn", "Skipping corrupt task journal record at %s:%d: %v", path, line, err)
+			continue
+		}
+		records++
+		switch rec.Op {
+		case opPut:
+			if rec.Task != nil {
+				tasks[rec.Task.ID] = rec.Task
+			}
+		case opDelete:
+			delete(tasks, rec.ID)
+		}
+	}
+	return tasks, records, sc.Err()
+}
+
+// put records the current state of t.
+func (j *journal) put(t *Task) error {
+	return j.append(record{Op: opPut, Task: t})
+}
+
+// delete records the removal of the task id.
+func (j *journal) delete(id string) error {
+	return j.append(record{Op: opDelete, ID: id})
+}
+
+func (j *journal) append(rec record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := j.f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	j.records++
+	return j.f.Sync()
+}
+
+// needsCompaction reports whether most records of the journal are stale.
+func (j *journal) needsCompaction(live int) bool {
+	return j.records > 1000 && j.records > 4*live
+}
+
+// compact rewrites the journal with one put per live task, replacing the
+// old file atomically, and reopens it for appending.
+func (j *journal) compact(tasks map[string]*Task) error {
+	tmp := j.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, t := range sorted(tasks) {
+		if err := enc.Encode(record{Op: opPut, Task: t}); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		return err
+	}
+	// The rename is only durable once the directory entry is on disk.
+	if err := syncDir(filepath.Dir(j.path)); err != nil {
+		return err
+	}
+
+	if j.f != nil {
+		_ = j.f.Close()
+	}
+	j.f, err = os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	j.records = len(tasks)
+	return nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// close closes the journal and releases the lock.
+func (j *journal) close() error {
+	err := j.f.Close()
+	if lerr := j.lock.Close(); err == nil {
+		err = lerr
+	}
+	return err
+}
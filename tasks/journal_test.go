@@ -0,0 +1,150 @@
+package tasks
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestReadJournal(t *testing.T) {
+	tests := []struct {
+		name        string
+		lines       []string
+		wantIDs     []string
+		wantRecords int
+	}{
+		{name: "missing file"},
+		{
+			name: "puts and deletes",
+			lines: []string{
+				`{"op":"put","task":{"id":"a","type":"mail","state":"pending"}}`,
+				`{"op":"put","task":{"id":"b","type":"mail","state":"pending"}}`,
+				`{"op":"delete","id":"a"}`,
+			},
+			wantIDs:     []string{"b"},
+			wantRecords: 3,
+		},
+		{
+			name: "later put wins",
+			lines: []string{
+				`{"op":"put","task":{"id":"a","type":"mail","state":"pending"}}`,
+				`{"op":"put","task":{"id":"a","type":"mail","state":"dead","attempts":5}}`,
+			},
+			wantIDs:     []string{"a"},
+			wantRecords: 2,
+		},
+		{
+			name: "torn and unknown records are skipped",
+			lines: []string{
+				`{"op":"put","task":{"id":"a","type":"mail","state":"pending"}}`,
+				`{"op":"put","task":{"id":"b","ty`,
+				`{"op":"rename","id":"a"}`,
+			},
+			wantIDs:     []string{"a"},
+			wantRecords: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tasks.jsonl")
+			if tt.lines != nil {
+				if err := os.WriteFile(path, []byte(strings.Join(tt.lines, "\n")+"\n"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tasks, records, err := readJournal(path)
+			if err != nil {
+				t.Fatalf("readJournal() error = %v", err)
+			}
+			ids := make([]string, 0, len(tasks))
+			for id := range tasks {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("tasks = %v, want %v", ids, tt.wantIDs)
+			}
+			if records != tt.wantRecords {
+				t.Errorf("records = %d, want %d", records, tt.wantRecords)
+			}
+		})
+	}
+	t.Run("replayed state", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tasks.jsonl")
+		data := `{"op":"put","task":{"id":"a","type":"mail","state":"pending"}}
+{"op":"put","task":{"id":"a","type":"mail","state":"dead","attempts":5,"last_error":"boom"}}
+`
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		tasks, _, err := readJournal(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a := tasks["a"]; a.State != StateDead || a.Attempts != 5 || a.LastError != "boom" {
+			t.Errorf("task a = %+v, want the last put", a)
+		}
+	})
+}
+
+func TestJournalCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.jsonl")
+	j, tasks, err := openJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = j.close() }()
+
+	keep := &Task{ID: "keep", Type: "mail", State: StatePending}
+	tasks[keep.ID] = keep
+	for i := 0; i < 1200; i++ {
+		if err := j.put(keep); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !j.needsCompaction(len(tasks)) {
+		t.Fatalf("needsCompaction() = false with %d records for %d task", j.records, len(tasks))
+	}
+	if err := j.compact(tasks); err != nil {
+		t.Fatalf("compact() error = %v", err)
+	}
+	if j.records != 1 || j.needsCompaction(len(tasks)) {
+		t.Errorf("records after compaction = %d, want 1", j.records)
+	}
+
+	// The journal stays appendable after the rename.
+	if err := j.delete(keep.ID); err != nil {
+		t.Fatal(err)
+	}
+	replayed, records, err := readJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed) != 0 || records != 2 {
+		t.Errorf("replayed %d tasks from %d records, want 0 from 2", len(replayed), records)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestJournalLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.jsonl")
+	j, _, err := openJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := openJournal(path); err != ErrLocked {
+		t.Errorf("second openJournal() error = %v, want ErrLocked", err)
+	}
+	if err := j.close(); err != nil {
+		t.Fatal(err)
+	}
+	j, _, err = openJournal(path)
+	if err != nil {
+		t.Fatalf("openJournal() after close error = %v", err)
+	}
+	_ = j.close()
+}
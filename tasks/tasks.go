@@ -0,0 +1,670 @@
+// Package tasks implements a durable background task queue stored in an
+// append-only journal under the data directory, with no external broker.
+// Modules register a handler per task type and enqueue tasks; the worker
+// pool run by the start command processes them with retries, exponential
+// backoff and a dead-letter state. Delivery is at least once: a task that
+// was running when the process died is run again after a restart.
+package tasks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rafa-mori/goforge/config"
+	gl "github.com/rafa-mori/goforge/logger"
+	"github.com/rafa-mori/goforge/metrics"
+	"github.com/rafa-mori/goforge/paths"
+)
+
+// ErrUnknownTask is returned for a task id that is not in the queue.
+var ErrUnknownTask = errors.New("unknown task")
+
+// ErrStopped is returned by the operations of a queue after Stop.
+var ErrStopped = errors.New("task queue is stopped")
+
+// State is the state of a task in the queue. Completed tasks are removed.
+type State string
+
+const (
+	// StatePending tasks wait for a worker, possibly until NextAttemptAt.
+	StatePending State = "pending"
+	// StateRunning tasks are being processed by a worker.
+	StateRunning State = "running"
+	// StateDead tasks failed MaxAttempts times and wait for a manual retry
+	// or purge.
+	StateDead State = "dead"
+)
+
+// Configuration keys of the worker pool and the retry policy.
+const (
+	ConfigKeyWorkers        = "tasks.workers"
+	ConfigKeyMaxAttempts    = "tasks.max_attempts"
+	ConfigKeyInitialBackoff = "tasks.initial_backoff"
+	ConfigKeyMaxBackoff     = "tasks.max_backoff"
+	ConfigKeyTimeout        = "tasks.timeout"
+)
+
+func init() {
+	config.SetDefault(ConfigKeyWorkers, 4)
+	config.SetDefault(ConfigKeyMaxAttempts, 5)
+	config.SetDefault(ConfigKeyInitialBackoff, "1s")
+	config.SetDefault(ConfigKeyMaxBackoff, "10m")
+	config.SetDefault(ConfigKeyTimeout, "5m")
+}
+
+var (
+	taskRuns     = metrics.NewCounter(metrics.Name("task_runs_total"), "Task runs by type and outcome.", "type", "outcome")
+	taskDuration = metrics.NewHistogram(metrics.Name("task_duration_seconds"), "Duration of task runs.", nil, "type")
+)
+
+// Task is a unit of work in the queue.
+type Task struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	State         State           `json:"state"`
+	Attempts      int             `json:"attempts"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+}
+
+// Handler processes a task. A returned error or a panic schedules a retry.
+type Handler func(ctx context.Context, t *Task) error
+
+// Options tunes the worker pool.
+type Options struct {
+	// Workers is the number of tasks processed concurrently.
+	Workers int
+	// MaxAttempts is how many times a task is tried before it is moved to
+	// the dead state.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. It doubles after
+	// every failure up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Timeout bounds a single run; zero means no timeout.
+	Timeout time.Duration
+}
+
+// OptionsFromConfig reads the queue Options from the tasks section.
+func OptionsFromConfig() Options {
+	c := config.Get()
+	return Options{
+		Workers:        c.GetInt(ConfigKeyWorkers),
+		MaxAttempts:    c.GetInt(ConfigKeyMaxAttempts),
+		InitialBackoff: c.GetDuration(ConfigKeyInitialBackoff),
+		MaxBackoff:     c.GetDuration(ConfigKeyMaxBackoff),
+		Timeout:        c.GetDuration(ConfigKeyTimeout),
+	}
+}
+
+func (o Options) withDefaults() Options {
+	if o.Workers <= 0 {
+		o.Workers = 4
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 5
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = time.Second
+	}
+	if o.MaxBackoff < o.InitialBackoff {
+		o.MaxBackoff = o.InitialBackoff
+	}
+	return o
+}
+
+// backoff returns the delay before the retry following the given number
+// of failed attempts.
+func (o Options) backoff(attempts int) time.Duration {
+	d := o.InitialBackoff
+	for i := 1; i < attempts && d < o.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, o.MaxBackoff)
+}
+
+// DefaultPath returns the location of the journal of the Default queue.
+func DefaultPath() string {
+	return filepath.Join(paths.DataDir(), "tasks", "queue.log")
+}
+
+// Queue is a durable task queue. It implements goforge.Lifecycle: Init
+// opens the journal and Start runs the worker pool.
+type Queue struct {
+	path string
+	opts Options
+	deps []string
+
+	mu       sync.Mutex
+	journal  *journal
+	tasks    map[string]*Task
+	handlers map[string]Handler
+	changed  chan struct{}
+	// stopped is set by Stop, so that the journal is not reopened behind
+	// a stopping service.
+	stopped bool
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Default is the queue used by the package-level functions. Its journal
+// lives at DefaultPath and its Options come from the configuration.
+var Default = New("", Options{})
+
+// New returns a Queue backed by the journal at path, or DefaultPath when
+// path is "". Zero Options are read from the configuration on Init.
+func New(path string, opts Options) *Queue {
+	return &Queue{
+		path:     path,
+		opts:     opts,
+		handlers: make(map[string]Handler),
+		changed:  make(chan struct{}),
+	}
+}
+
+// Handle registers the handler of a task type on the Default queue.
+func Handle(typ string, h Handler) { Default.Handle(typ, h) }
+
+// HandleFunc registers a handler receiving the decoded payload of a task
+// type on the Default queue.
+func HandleFunc[T any](typ string, fn func(ctx context.Context, payload T) error) {
+	Default.Handle(typ, func(ctx context.Context, t *Task) error {
+		var payload T
+		if len(t.Payload) > 0 {
+			if err := json.Unmarshal(t.Payload, &payload); err != nil {
+				return fmt.Errorf("decode %s payload: %w", typ, err)
+			}
+		}
+		return fn(ctx, payload)
+	})
+}
+
+// Enqueue adds a task to the Default queue.
+func Enqueue(typ string, payload any) (*Task, error) { return Default.Enqueue(typ, payload) }
+
+// Handle registers the handler of a task type. Tasks of types without a
+// handler stay pending.
+func (q *Queue) Handle(typ string, h Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[typ] = h
+	q.notify()
+}
+
+// DependsOn names the modules the task handlers use. The workers start
+// after them.
+func (q *Queue) DependsOn(modules ...string) {
+	q.deps = append(q.deps, modules...)
+}
+
+// Dependencies implements goforge.Dependent.
+func (q *Queue) Dependencies() []string { return q.deps }
+
+// Path returns the location of the journal.
+func (q *Queue) Path() string {
+	if q.path == "" {
+		return DefaultPath()
+	}
+	return q.path
+}
+
+// Init opens the journal, also after Stop. Tasks left running by a
+// previous process are made pending again.
+func (q *Queue) Init() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.stopped = false
+	return q.open()
+}
+
+// open opens the journal if needed. q.mu must be held.
+func (q *Queue) open() error {
+	if q.journal != nil {
+		return nil
+	}
+	if q.stopped {
+		return ErrStopped
+	}
+	if q.opts == (Options{}) {
+		q.opts = OptionsFromConfig()
+	}
+	q.opts = q.opts.withDefaults()
+
+	j, tasks, err := openJournal(q.Path())
+	if err != nil {
+		return fmt.Errorf("open task queue %s: %w", q.Path(), err)
+	}
+	q.journal, q.tasks = j, tasks
+	for _, t := range sorted(tasks) {
+		if t.State == StateRunning {
+			t.State = StatePending
+			if err := q.journal.put(t); err != nil {
+				return err
+			}
+			gl.Log("warn", "Task "+t.ID+" ("+t.Type+") was interrupted and will run again")
+		}
+	}
+	return nil
+}
+
+// Start launches the workers.
+func (q *Queue) Start(ctx context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.open(); err != nil {
+		return err
+	}
+	ctx, q.cancel = context.WithCancel(ctx)
+	for i := 0; i < q.opts.Workers; i++ {
+		q.wg.Add(1)
+		go q.work(ctx)
+	}
+	gl.Logf("debug", "Task queue started with %d workers and %d tasks", q.opts.Workers, len(q.tasks))
+	return nil
+}
+
+// Stop stops the workers, waiting for the running tasks, whose context is
+// cancelled, until ctx's deadline, and closes the journal even when some
+// are still running. Tasks that did not finish run again on the next
+// start. Later operations fail with ErrStopped until Init is called again.
+func (q *Queue) Stop(ctx context.Context) error {
+	q.mu.Lock()
+	cancel := q.cancel
+	q.cancel = nil
+	q.mu.Unlock()
+
+	var waitErr error
+	if cancel != nil {
+		cancel()
+		done := make(chan struct{})
+		go func() {
+			q.wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			waitErr = fmt.Errorf("tasks still running: %w", ctx.Err())
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.stopped = true
+	if q.journal == nil {
+		return waitErr
+	}
+	err := q.journal.close()
+	q.journal = nil
+	return errors.Join(waitErr, err)
+}
+
+// Health reports whether the journal is open.
+func (q *Queue) Health() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.journal == nil {
+		return errors.New("task queue is not open")
+	}
+	return nil
+}
+
+// Enqueue adds a task of type typ with payload encoded as JSON. The task
+// is on disk when Enqueue returns.
+func (q *Queue) Enqueue(typ string, payload any) (*Task, error) {
+	if typ == "" {
+		return nil, errors.New("task type is required")
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s payload: %w", typ, err)
+	}
+	now := time.Now()
+	t := &Task{
+		ID:            newID(),
+		Type:          typ,
+		Payload:       data,
+		State:         StatePending,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		NextAttemptAt: now,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.open(); err != nil {
+		return nil, err
+	}
+	if err := q.journal.put(t); err != nil {
+		return nil, err
+	}
+	q.tasks[t.ID] = t
+	q.notify()
+	return clone(t), nil
+}
+
+// List returns the tasks in the given states, or every task when no state
+// is given, oldest first.
+func (q *Queue) List(states ...State) ([]Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.open(); err != nil {
+		return nil, err
+	}
+	return filter(q.tasks, states), nil
+}
+
+// Retry makes the given dead tasks, or every dead task when no id is
+// given, pending again with a fresh attempt count. It returns the ids of
+// the retried tasks.
+func (q *Queue) Retry(ids ...string) ([]string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.open(); err != nil {
+		return nil, err
+	}
+	targets, err := q.pick(ids, StateDead)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	retried := make([]string, 0, len(targets))
+	for _, t := range targets {
+		if t.State != StateDead {
+			return retried, fmt.Errorf("task %s is %s, only dead tasks can be retried", t.ID, t.State)
+		}
+		t.State = StatePending
+		t.Attempts = 0
+		t.UpdatedAt = now
+		t.NextAttemptAt = now
+		if err := q.journal.put(t); err != nil {
+			return retried, err
+		}
+		retried = append(retried, t.ID)
+	}
+	q.notify()
+	return retried, nil
+}
+
+// Purge removes the given tasks, or every task in one of states when no
+// id is given. Running tasks cannot be purged. It returns the ids of the
+// removed tasks.
+func (q *Queue) Purge(ids []string, states ...State) ([]string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.open(); err != nil {
+		return nil, err
+	}
+	targets, err := q.pick(ids, states...)
+	if err != nil {
+		return nil, err
+	}
+	purged := make([]string, 0, len(targets))
+	for _, t := range targets {
+		if t.State == StateRunning {
+			if len(ids) > 0 {
+				return purged, fmt.Errorf("task %s is running and cannot be purged", t.ID)
+			}
+			continue
+		}
+		if err := q.journal.delete(t.ID); err != nil {
+			return purged, err
+		}
+		delete(q.tasks, t.ID)
+		purged = append(purged, t.ID)
+	}
+	return purged, q.maybeCompact()
+}
+
+// pick returns the tasks named by ids, or the tasks in states when ids
+// is empty. q.mu must be held.
+func (q *Queue) pick(ids []string, states ...State) ([]*Task, error) {
+	var list []*Task
+	if len(ids) == 0 {
+		for _, t := range sorted(q.tasks) {
+			if matches(t, states) {
+				list = append(list, t)
+			}
+		}
+		return list, nil
+	}
+	for _, id := range ids {
+		t, ok := q.tasks[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownTask, id)
+		}
+		list = append(list, t)
+	}
+	return list, nil
+}
+
+// work processes tasks until ctx is done.
+func (q *Queue) work(ctx context.Context) {
+	defer q.wg.Done()
+	for {
+		t, wait, changed := q.claim()
+		if t != nil {
+			q.process(ctx, t)
+			continue
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-changed:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// claim marks the oldest due task with a handler as running. When there is
+// none it returns how long to wait for the next one and a channel closed
+// on the next change of the queue.
+func (q *Queue) claim() (*Task, time.Duration, <-chan struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	wait := time.Minute
+	if q.journal == nil {
+		// Stopped: the worker exits once its context is done.
+		return nil, wait, q.changed
+	}
+	var next *Task
+	for _, t := range q.tasks {
+		if t.State != StatePending || q.handlers[t.Type] == nil {
+			continue
+		}
+		if t.NextAttemptAt.After(now) {
+			wait = min(wait, t.NextAttemptAt.Sub(now))
+			continue
+		}
+		if next == nil || t.NextAttemptAt.Before(next.NextAttemptAt) {
+			next = t
+		}
+	}
+	if next == nil {
+		return nil, wait, q.changed
+	}
+	next.State = StateRunning
+	next.UpdatedAt = now
+	if err := q.journal.put(next); err != nil {
+		gl.Log("error", "Failed to record task "+next.ID+" as running: "+err.Error())
+	}
+	return clone(next), 0, nil
+}
+
+// process runs t and records the outcome.
+func (q *Queue) process(ctx context.Context, t *Task) {
+	q.mu.Lock()
+	h := q.handlers[t.Type]
+	q.mu.Unlock()
+
+	runCtx := ctx
+	if q.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, q.opts.Timeout)
+		defer cancel()
+	}
+
+	lgr := gl.FromContext(ctx).With("task_id", t.ID, "task_type", t.Type, "attempt", t.Attempts+1)
+	runCtx = gl.IntoContext(runCtx, lgr)
+	lgr.Log("debug", "Task started")
+	started := time.Now()
+	err := runHandler(runCtx, h, t)
+	if err == nil && q.opts.Timeout > 0 && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", q.opts.Timeout)
+	}
+	elapsed := time.Since(started)
+	taskDuration.Observe(elapsed.Seconds(), t.Type)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.journal == nil {
+		// Stop gave up waiting and closed the journal; the task is still
+		// recorded as running and runs again on the next start.
+		lgr.Log("warn", "Task finished after the queue stopped, its outcome is lost")
+		return
+	}
+	cur, ok := q.tasks[t.ID]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	cur.UpdatedAt = now
+
+	switch {
+	case err == nil:
+		taskRuns.Inc(t.Type, "success")
+		lgr.LogKV("info", "Task finished", "duration", elapsed.String())
+		if jerr := q.journal.delete(cur.ID); jerr != nil {
+			lgr.Log("error", "Failed to record task as done: "+jerr.Error())
+		}
+		delete(q.tasks, cur.ID)
+		if jerr := q.maybeCompact(); jerr != nil {
+			gl.Log("error", "Failed to compact the task journal: "+jerr.Error())
+		}
+		return
+	case ctx.Err() != nil:
+		// Shutting down: the task was not given a fair chance, so it runs
+		// again on the next start without consuming an attempt.
+		cur.State = StatePending
+		cur.NextAttemptAt = now
+		lgr.Log("warn", "Task interrupted by shutdown")
+	default:
+		cur.Attempts++
+		cur.LastError = err.Error()
+		if cur.Attempts >= q.opts.MaxAttempts {
+			cur.State = StateDead
+			taskRuns.Inc(t.Type, "dead")
+			lgr.LogKV("error", "Task failed too many times, moved to the dead-letter state", "attempts", cur.Attempts, "error", err.Error())
+		} else {
+			delay := q.opts.backoff(cur.Attempts)
+			cur.State = StatePending
+			cur.NextAttemptAt = now.Add(delay)
+			taskRuns.Inc(t.Type, "retry")
+			lgr.LogKV("warn", "Task failed, retrying", "retry_in", delay.String(), "error", err.Error())
+		}
+	}
+	if jerr := q.journal.put(cur); jerr != nil {
+		lgr.Log("error", "Failed to record task: "+jerr.Error())
+	}
+}
+
+func runHandler(ctx context.Context, h Handler, t *Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			gl.FromContext(ctx).LogKV("debug", "Task panicked", "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+		}
+	}()
+	return h(ctx, t)
+}
+
+// maybeCompact compacts the journal when it holds mostly stale records.
+// q.mu must be held.
+func (q *Queue) maybeCompact() error {
+	if !q.journal.needsCompaction(len(q.tasks)) {
+		return nil
+	}
+	return q.journal.compact(q.tasks)
+}
+
+// notify wakes the waiting workers. q.mu must be held.
+func (q *Queue) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// Read returns the tasks of the journal at path in the given states
+// without opening the queue, so it works while another process owns it.
+func Read(path string, states ...State) ([]Task, error) {
+	tasks, _, err := readJournal(path)
+	if err != nil {
+		return nil, err
+	}
+	return filter(tasks, states), nil
+}
+
+func filter(tasks map[string]*Task, states []State) []Task {
+	list := make([]Task, 0, len(tasks))
+	for _, t := range sorted(tasks) {
+		if matches(t, states) {
+			list = append(list, *clone(t))
+		}
+	}
+	return list
+}
+
+func matches(t *Task, states []State) bool {
+	if len(states) == 0 {
+		return true
+	}
+	for _, s := range states {
+		if t.State == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sorted returns the tasks oldest first.
+func sorted(tasks map[string]*Task) []*Task {
+	list := make([]*Task, 0, len(tasks))
+	for _, t := range tasks {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+func clone(t *Task) *Task {
+	c := *t
+	c.Payload = append(json.RawMessage(nil), t.Payload...)
+	return &c
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
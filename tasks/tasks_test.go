@@ -0,0 +1,244 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestQueue(t *testing.T) *Queue {
+	t.Helper()
+	q := New(filepath.Join(t.TempDir(), "tasks.jsonl"), Options{Workers: 1, MaxAttempts: 2, InitialBackoff: time.Millisecond})
+	if err := q.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func TestQueueProcessesAndPersists(t *testing.T) {
+	q := newTestQueue(t)
+	done := make(chan string, 1)
+	q.Handle("echo", func(_ context.Context, t *Task) error {
+		var payload string
+		if err := json.Unmarshal(t.Payload, &payload); err != nil {
+			return err
+		}
+		done <- payload
+		return nil
+	})
+	if _, err := q.Enqueue("echo", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-done:
+		if got != "hello" {
+			t.Errorf("payload = %q, want hello", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("task did not run")
+	}
+	if err := q.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	left, err := Read(q.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 0 {
+		t.Errorf("journal still holds %d tasks after success", len(left))
+	}
+}
+
+func TestQueueAfterStop(t *testing.T) {
+	q := newTestQueue(t)
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Enqueue("mail", nil); !errors.Is(err, ErrStopped) {
+		t.Fatalf("Enqueue() after Stop error = %v, want ErrStopped", err)
+	}
+	// The lock is released, so another owner can open the journal.
+	j, _, err := openJournal(q.Path())
+	if err != nil {
+		t.Fatalf("openJournal() after Stop error = %v", err)
+	}
+	_ = j.close()
+
+	if err := q.Init(); err != nil {
+		t.Fatalf("Init() after Stop error = %v", err)
+	}
+	if _, err := q.Enqueue("mail", nil); err != nil {
+		t.Errorf("Enqueue() after Init error = %v", err)
+	}
+	_ = q.Stop(context.Background())
+}
+
+func TestQueueStopTimeoutClosesJournal(t *testing.T) {
+	q := newTestQueue(t)
+	started, release := make(chan struct{}), make(chan struct{})
+	q.Handle("stuck", func(context.Context, *Task) error {
+		close(started)
+		<-release
+		return nil
+	})
+	defer close(release)
+	if _, err := q.Enqueue("stuck", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := q.Stop(ctx); err == nil {
+		t.Fatal("Stop() returned nil while a task was still running")
+	}
+	j, tasks, err := openJournal(q.Path())
+	if err != nil {
+		t.Fatalf("journal still locked after Stop timed out: %v", err)
+	}
+	defer func() { _ = j.close() }()
+	if len(tasks) != 1 {
+		t.Errorf("journal holds %d tasks, want the interrupted one", len(tasks))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	opts := Options{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second}.withDefaults()
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := opts.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// waitFor polls the queue until cond holds for the tasks in states.
+func waitFor(t *testing.T, q *Queue, cond func([]Task) bool, states ...State) []Task {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		tasks, err := q.List(states...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cond(tasks) {
+			return tasks
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met, tasks: %+v", tasks)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestQueueRetriesThenDeadLetters(t *testing.T) {
+	const backoff = 40 * time.Millisecond
+	q := New(filepath.Join(t.TempDir(), "tasks.jsonl"), Options{Workers: 2, MaxAttempts: 3, InitialBackoff: backoff, MaxBackoff: time.Second})
+	if err := q.Init(); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu        sync.Mutex
+		attempts  []time.Time
+		flakyRuns int
+	)
+	q.Handle("broken", func(context.Context, *Task) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, time.Now())
+		return errors.New("always failing")
+	})
+	q.Handle("flaky", func(context.Context, *Task) error {
+		mu.Lock()
+		defer mu.Unlock()
+		flakyRuns++
+		if flakyRuns == 1 {
+			panic("first run")
+		}
+		return nil
+	})
+	broken, err := q.Enqueue("broken", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Enqueue("flaky", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = q.Stop(context.Background()) })
+
+	dead := waitFor(t, q, func(ts []Task) bool { return len(ts) == 1 }, StateDead)
+	if d := dead[0]; d.ID != broken.ID || d.Attempts != 3 || d.LastError != "always failing" {
+		t.Errorf("dead task = %+v", d)
+	}
+	// The flaky task succeeded on its retry and left the queue.
+	waitFor(t, q, func(ts []Task) bool { return len(ts) == 0 }, StatePending, StateRunning)
+	mu.Lock()
+	defer mu.Unlock()
+	if flakyRuns != 2 {
+		t.Errorf("flaky task ran %d times, want 2", flakyRuns)
+	}
+	if len(attempts) != 3 {
+		t.Fatalf("broken task ran %d times, want 3", len(attempts))
+	}
+	for i, want := range []time.Duration{backoff, 2 * backoff} {
+		if gap := attempts[i+1].Sub(attempts[i]); gap < want {
+			t.Errorf("retry %d came after %s, want at least %s", i+1, gap, want)
+		}
+	}
+
+}
+
+func TestRetryResetsAttempts(t *testing.T) {
+	q := newTestQueue(t)
+	q.Handle("broken", func(context.Context, *Task) error { return errors.New("always failing") })
+	task, err := q.Enqueue("broken", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = q.Stop(context.Background()) })
+	dead := waitFor(t, q, func(ts []Task) bool { return len(ts) == 1 }, StateDead)
+
+	retried, err := q.Retry()
+	if err != nil || len(retried) != 1 || retried[0] != task.ID {
+		t.Fatalf("Retry() = %v, %v", retried, err)
+	}
+	// The retried task runs MaxAttempts times again before dying.
+	again := waitFor(t, q, func(ts []Task) bool {
+		return len(ts) == 1 && ts[0].UpdatedAt.After(dead[0].UpdatedAt)
+	}, StateDead)
+	if again[0].Attempts != 2 {
+		t.Errorf("attempts after retry = %d, want 2", again[0].Attempts)
+	}
+	if _, err := q.Retry(task.ID, "missing"); err == nil {
+		t.Error("Retry() of an unknown id succeeded")
+	}
+}
@@ -3,20 +3,29 @@
 package version
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/rafa-mori/goforge/daemon"
+	"github.com/rafa-mori/goforge/events"
 	manifest "github.com/rafa-mori/goforge/info"
 	"github.com/rafa-mori/goforge/logger"
+	"github.com/rafa-mori/goforge/metrics"
 	"github.com/spf13/cobra"
 )
 
 var gl = logger.GetLogger[ServiceImpl](nil)
+
+// versionChecks counts the outcomes of IsLatestVersion: latest, outdated
+// or error.
+var versionChecks = metrics.NewCounter(metrics.Name("version_checks_total"), "Outcomes of checks for a newer version.", "outcome")
 var (
 	info manifest.Manifest
 	vrs  Service
@@ -35,10 +44,14 @@ func init() {
 type Service interface {
 	// GetLatestVersion retrieves the latest version from the Git repository.
 	GetLatestVersion() (string, error)
+	// GetLatestVersionContext is GetLatestVersion bound to ctx.
+	GetLatestVersionContext(ctx context.Context) (string, error)
 	// GetCurrentVersion returns the current version of the service.
 	GetCurrentVersion() string
 	// IsLatestVersion checks if the current version is the latest version.
 	IsLatestVersion() (bool, error)
+	// IsLatestVersionContext is IsLatestVersion bound to ctx.
+	IsLatestVersionContext(ctx context.Context) (bool, error)
 	// GetName returns the name of the service.
 	GetName() string
 	// GetVersion returns the current version of the service.
@@ -48,7 +61,7 @@ type Service interface {
 	// setLastCheckedAt sets the last checked time for the version.
 	setLastCheckedAt(time.Time)
 	// updateLatestVersion updates the latest version from the Git repository.
-	updateLatestVersion() error
+	updateLatestVersion(ctx context.Context) error
 }
 type ServiceImpl struct {
 	manifest.Manifest
@@ -56,6 +69,11 @@ type ServiceImpl struct {
 	latestVersion  string
 	lastCheckedAt  time.Time
 	currentVersion string
+
+	// announced is the latest version TopicVersionAvailable was last
+	// published for, so repeated checks publish it once.
+	mu        sync.Mutex
+	announced string
 }
 
 func init() {
@@ -71,10 +89,10 @@ func init() {
 	}
 }
 
-func getLatestTag(repoURL string) (string, error) {
+func getLatestTag(ctx context.Context, repoURL string) (string, error) {
 	defer func() {
 		if rec := recover(); rec != nil {
-			gl.Log("error", "Recovered from panic in getLatestTag: %v", rec)
+			gl.Logf("error", "Recovered from panic in getLatestTag: %v", rec)
 			err = fmt.Errorf("panic occurred while fetching latest tag: %v", rec)
 		}
 	}()
@@ -105,7 +123,11 @@ func getLatestTag(repoURL string) (string, error) {
 	}
 
 	apiURL := fmt.Sprintf("%s/tags", repoURL)
-	resp, err := http.Get(apiURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -137,12 +159,12 @@ func getLatestTag(repoURL string) (string, error) {
 	}
 	return tags[0].Name, nil
 }
-func (v *ServiceImpl) updateLatestVersion() error {
+func (v *ServiceImpl) updateLatestVersion(ctx context.Context) error {
 	if info.IsPrivate() {
 		return fmt.Errorf("cannot fetch latest version for private repositories")
 	}
 	repoURL := strings.TrimSuffix(v.gitModelURL, ".git")
-	tag, err := getLatestTag(repoURL)
+	tag, err := getLatestTag(ctx, repoURL)
 	if err != nil {
 		return err
 	}
@@ -163,14 +185,6 @@ func (v *ServiceImpl) vrsCompare(v1, v2 []int) (int, error) {
 	}
 	return compare, nil
 }
-func (v *ServiceImpl) versionAtMost(versionAtMostArg, max []int) (bool, error) {
-	if comp, err := v.vrsCompare(versionAtMostArg, max); err != nil {
-		return false, err
-	} else if comp == 1 {
-		return false, nil
-	}
-	return true, nil
-}
 func (v *ServiceImpl) parseVersion(versionToParse string) []int {
 	if versionToParse == "" {
 		return nil
@@ -193,11 +207,40 @@ func (v *ServiceImpl) parseVersion(versionToParse string) []int {
 	return parsedVersion
 }
 func (v *ServiceImpl) IsLatestVersion() (bool, error) {
+	return v.IsLatestVersionContext(context.Background())
+}
+func (v *ServiceImpl) IsLatestVersionContext(ctx context.Context) (bool, error) {
+	isLatest, err := v.isLatestVersion(ctx)
+	switch {
+	case err != nil:
+		versionChecks.Inc("error")
+	case isLatest:
+		versionChecks.Inc("latest")
+	default:
+		versionChecks.Inc("outdated")
+		v.announce()
+	}
+	return isLatest, err
+}
+
+// announce publishes TopicVersionAvailable unless it was already published
+// for the current latest version.
+func (v *ServiceImpl) announce() {
+	v.mu.Lock()
+	if v.announced == v.latestVersion {
+		v.mu.Unlock()
+		return
+	}
+	v.announced = v.latestVersion
+	v.mu.Unlock()
+	events.Publish(events.TopicVersionAvailable, events.VersionEvent{Current: v.currentVersion, Latest: v.latestVersion})
+}
+func (v *ServiceImpl) isLatestVersion(ctx context.Context) (bool, error) {
 	if info.IsPrivate() {
 		return false, fmt.Errorf("cannot check version for private repositories")
 	}
 	if v.latestVersion == "" {
-		if err := v.updateLatestVersion(); err != nil {
+		if err := v.updateLatestVersion(ctx); err != nil {
 			return false, err
 		}
 	}
@@ -213,14 +256,20 @@ func (v *ServiceImpl) IsLatestVersion() (bool, error) {
 		return false, fmt.Errorf("version parts length mismatch")
 	}
 
-	return v.versionAtMost(currentVersionParts, latestVersionParts)
+	// The current version is the latest unless it is older: a build ahead
+	// of the last tag is not outdated.
+	comp, err := v.vrsCompare(currentVersionParts, latestVersionParts)
+	return comp >= 0, err
 }
 func (v *ServiceImpl) GetLatestVersion() (string, error) {
+	return v.GetLatestVersionContext(context.Background())
+}
+func (v *ServiceImpl) GetLatestVersionContext(ctx context.Context) (string, error) {
 	if info.IsPrivate() {
 		return "", fmt.Errorf("cannot fetch latest version for private repositories")
 	}
 	if v.latestVersion == "" {
-		if err := v.updateLatestVersion(); err != nil {
+		if err := v.updateLatestVersion(ctx); err != nil {
 			return "", err
 		}
 	}
@@ -255,11 +304,25 @@ func (v *ServiceImpl) setLastCheckedAt(t time.Time) {
 	gl.Log("debug", "Last checked at: "+t.Format(time.RFC3339))
 }
 
+// GetService returns the shared version Service.
+func GetService() Service {
+	if vrs == nil {
+		vrs = NewVersionService()
+	}
+	return vrs
+}
+
 func NewVersionService() Service {
+	return NewService(info.GetRepository(), info.GetVersion())
+}
+
+// NewService returns a Service comparing current with the latest tag of
+// the repository at repoURL.
+func NewService(repoURL, current string) Service {
 	return &ServiceImpl{
 		Manifest:       info,
-		gitModelURL:    info.GetRepository(),
-		currentVersion: info.GetVersion(),
+		gitModelURL:    repoURL,
+		currentVersion: current,
 		latestVersion:  "",
 	}
 }
@@ -300,7 +363,7 @@ func init() {
 					gl.Log("error", "Cannot fetch latest version for private repositories.")
 					return
 				}
-				GetLatestVersionInfo()
+				GetLatestVersionInfoContext(cmd.Context())
 			},
 		}
 	}
@@ -314,7 +377,7 @@ func init() {
 					gl.Log("error", "Cannot check version for private repositories.")
 					return
 				}
-				GetVersionInfoWithLatestAndCheck()
+				GetVersionInfoWithLatestAndCheckContext(cmd.Context())
 			},
 		}
 	}
@@ -328,10 +391,10 @@ func init() {
 					gl.Log("error", "Cannot update version for private repositories.")
 					return
 				}
-				if err := vrs.updateLatestVersion(); err != nil {
+				if err := vrs.updateLatestVersion(cmd.Context()); err != nil {
 					gl.Log("error", "Failed to update version: "+err.Error())
 				} else {
-					latestVersion, err := vrs.GetLatestVersion()
+					latestVersion, err := vrs.GetLatestVersionContext(cmd.Context())
 					if err != nil {
 						gl.Log("error", "Failed to get latest version: "+err.Error())
 					} else {
@@ -355,13 +418,18 @@ func init() {
 	}
 	if restartCmd == nil {
 		restartCmd = &cobra.Command{
-			Use:   "restart",
-			Short: "Restart the " + info.GetName() + " service",
-			Long:  "Restart the " + info.GetName() + " service to apply any changes made.",
+			Use:        "restart",
+			Short:      "Restart the " + info.GetName() + " service",
+			Long:       "Restart the " + info.GetName() + " service to apply any changes made.",
+			Deprecated: "use \"" + info.GetBin() + " restart\" instead",
 			Run: func(cmd *cobra.Command, args []string) {
 				gl.Log("info", "Restarting the service...")
-				// Logic to restart the service can be added here
-				gl.Log("success", "Service restarted successfully")
+				rInfo, err := daemon.Restart(30*time.Second, []string{"start"})
+				if err != nil {
+					gl.Log("error", "Failed to restart the service: "+err.Error())
+					return
+				}
+				gl.Logf("success", "Service restarted successfully (pid %d)", rInfo.PID)
 			},
 		}
 	}
@@ -389,6 +457,9 @@ func GetVersionInfo() string {
 	return fmt.Sprintf("Version: %s\nGit repository: %s", GetVersion(), GetGitRepositoryModelURL())
 }
 func GetLatestVersionFromGit() string {
+	return GetLatestVersionFromGitContext(context.Background())
+}
+func GetLatestVersionFromGitContext(ctx context.Context) string {
 	if info.IsPrivate() {
 		gl.Log("error", "Cannot fetch latest version for private repositories.")
 		return "Cannot fetch latest version for private repositories."
@@ -404,7 +475,12 @@ func GetLatestVersionFromGit() string {
 		return "No repository URL set in the manifest."
 	}
 
-	response, err := netClient.Get(gitURLWithoutGit + "/releases/latest")
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, gitURLWithoutGit+"/releases/latest", nil)
+	if err != nil {
+		gl.Log("error", "Error building request: "+err.Error())
+		return err.Error()
+	}
+	response, err := netClient.Do(request)
 	if err != nil {
 		gl.Log("error", "Error fetching latest version: "+err.Error())
 		gl.Log("error", gitURLWithoutGit+"/releases/latest")
@@ -423,24 +499,31 @@ func GetLatestVersionFromGit() string {
 	return tag[len(tag)-1]
 }
 func GetLatestVersionInfo() string {
+	return GetLatestVersionInfoContext(context.Background())
+}
+func GetLatestVersionInfoContext(ctx context.Context) string {
 	if info.IsPrivate() {
 		gl.Log("error", "Cannot fetch latest version for private repositories.")
 		return "Cannot fetch latest version for private repositories."
 	}
-	gl.Log("info", "Latest version: "+GetLatestVersionFromGit())
-	return "Latest version: " + GetLatestVersionFromGit()
+	latestVersion := GetLatestVersionFromGitContext(ctx)
+	gl.Log("info", "Latest version: "+latestVersion)
+	return "Latest version: " + latestVersion
 }
 func GetVersionInfoWithLatestAndCheck() string {
+	return GetVersionInfoWithLatestAndCheckContext(context.Background())
+}
+func GetVersionInfoWithLatestAndCheckContext(ctx context.Context) string {
 	if info.IsPrivate() {
 		gl.Log("error", "Cannot check version for private repositories.")
 		return "Cannot check version for private repositories."
 	}
-	if GetVersion() == GetLatestVersionFromGit() {
+	if GetVersion() == GetLatestVersionFromGitContext(ctx) {
 		gl.Log("info", "You are using the latest version.")
-		return fmt.Sprintf("You are using the latest version.\n%s\n%s", GetVersionInfo(), GetLatestVersionInfo())
+		return fmt.Sprintf("You are using the latest version.\n%s\n%s", GetVersionInfo(), GetLatestVersionInfoContext(ctx))
 	} else {
 		gl.Log("warn", "You are using an outdated version.")
-		return fmt.Sprintf("You are using an outdated version.\n%s\n%s", GetVersionInfo(), GetLatestVersionInfo())
+		return fmt.Sprintf("You are using an outdated version.\n%s\n%s", GetVersionInfo(), GetLatestVersionInfoContext(ctx))
 	}
 }
 func CliCommand() *cobra.Command {